@@ -0,0 +1,417 @@
+package browser
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/surf/errors"
+	"golang.org/x/net/html/atom"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// Article is the main content of a page, such as a news story or blog post,
+// along with the metadata describing it.
+type Article struct {
+	// Title is the headline of the article.
+	Title string
+
+	// Byline is the name of the author.
+	Byline string
+
+	// Published is the time the article was published, or the zero time when
+	// the page doesn't say.
+	Published time.Time
+
+	// Image is the URL of the lead image, or nil.
+	Image *url.URL
+
+	// Content holds the elements making up the body of the article. It can be
+	// passed to the PlainText or Markdown functions.
+	Content *goquery.Selection
+}
+
+// Article finds the main content of the page, leaving out the navigation,
+// sidebars, comments and ads around it.
+//
+// Paragraphs of text are scored by their length and punctuation, and their
+// scores are given to their ancestors. Class names and ids such as "sidebar"
+// and "comment" lower the scores, and dense links lower them further. The
+// element with the best score is the content, along with its siblings that
+// also score well.
+//
+// The title, byline, published date and lead image are taken from the meta
+// tags and JSON-LD data of the page when available, and from the page content
+// otherwise. An ElementNotFound error is returned when the page has no text
+// content.
+func (bow *Browser) Article() (*Article, error) {
+	nodes := articleContent(bow.Find("body"))
+	if len(nodes) == 0 {
+		return nil, errors.NewElementNotFound("No article content found in the page.")
+	}
+	content := bow.Dom().FindNodes(nodes...)
+
+	a := &Article{Content: content}
+	ld := articleItem(bow)
+	og := bow.OpenGraph()
+	tw := bow.TwitterCard()
+
+	a.Title = firstString(firstValue(og["og:title"]), firstValue(tw["twitter:title"]), itemString(ld, "headline"))
+	if a.Title == "" {
+		a.Title = cleanTitle(collapseSpace(bow.Title()))
+	}
+	if a.Title == "" {
+		a.Title = collapseSpace(bow.Find("h1").First().Text())
+	}
+
+	a.Byline = firstString(itemString(ld, "author"), metaContent(bow, `meta[name="author"]`), articleByline(bow))
+
+	for _, s := range []string{
+		firstValue(og["article:published_time"]),
+		itemString(ld, "datePublished"),
+		metaContent(bow, `meta[itemprop="datePublished"],meta[name="date"],meta[name="pubdate"],`+
+			`meta[name="publishdate"],meta[name="dc.date"],meta[name="dc.date.issued"],meta[name="parsely-pub-date"]`),
+		bow.Find("time[pubdate][datetime],[itemprop=datePublished][datetime]").AttrOr("datetime", ""),
+		content.Find("time[datetime]").AttrOr("datetime", ""),
+	} {
+		if t, ok := parseArticleTime(s); ok {
+			a.Published = t
+			break
+		}
+	}
+
+	image := firstString(firstValue(og["og:image"]), firstValue(tw["twitter:image"]), firstValue(tw["twitter:image:src"]), itemString(ld, "image"))
+	if image == "" {
+		if src, ok := content.Find("img[src]").Attr("src"); ok {
+			image = bow.resolveIRI(src)
+		}
+	}
+	if image != "" {
+		if u, err := url.Parse(image); err == nil {
+			a.Image = u
+		}
+	}
+
+	return a, nil
+}
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote`)
+	maybeCandidates    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveCandidates = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeCandidates = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	bylineCandidates   = regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+	titleSeparators    = regexp.MustCompile(` [|\-–—\\/>»:] `)
+)
+
+// articleContent scores the elements inside of the selection and returns the
+// nodes making up the main content, in document order.
+func articleContent(sel *goquery.Selection) []*nethtml.Node {
+	scores := make(map[*nethtml.Node]float64)
+	var candidates []*nethtml.Node
+	addScore := func(n *nethtml.Node, score float64) {
+		if _, ok := scores[n]; !ok {
+			scores[n] = baseScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type != nethtml.ElementNode || isHiddenElement(n) || isUnlikelyCandidate(n) {
+			return
+		}
+		if isScorable(n) {
+			text := collapseSpace(nodeText(n))
+			if len(text) >= 25 {
+				score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+				level := 0
+				for p := n.Parent; p != nil && p.Type == nethtml.ElementNode && level < 5; p = p.Parent {
+					if p.DataAtom == atom.Html {
+						break
+					}
+					divider := 1.0
+					if level == 1 {
+						divider = 2
+					} else if level > 1 {
+						divider = float64(level * 3)
+					}
+					addScore(p, score/divider)
+					level++
+				}
+			}
+			if n.DataAtom != atom.Div {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range sel.Nodes {
+		walk(n)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var top *nethtml.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > scores[top] {
+			top = n
+		}
+	}
+
+	// Siblings of the top candidate that score well, or that are paragraphs
+	// with few links, are part of the content too.
+	if top.Parent == nil || top.DataAtom == atom.Body {
+		return []*nethtml.Node{top}
+	}
+	threshold := math.Max(10, scores[top]*0.2)
+	topClass, _ := nodeAttr(top, "class")
+	var nodes []*nethtml.Node
+	for s := top.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != nethtml.ElementNode || isHiddenElement(s) {
+			continue
+		}
+		include := s == top
+		if !include {
+			score, scored := scores[s]
+			if class, _ := nodeAttr(s, "class"); scored && class != "" && class == topClass {
+				score += scores[top] * 0.2
+			}
+			if scored && score >= threshold {
+				include = true
+			} else if s.DataAtom == atom.P {
+				text := collapseSpace(nodeText(s))
+				include = len(text) > 80 && linkDensity(s) < 0.25
+			}
+		}
+		if include {
+			nodes = append(nodes, s)
+		}
+	}
+	return nodes
+}
+
+// baseScore returns the score an element starts with, based on its tag and
+// the class names and id which suggest it is or isn't content.
+func baseScore(n *nethtml.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	for _, name := range []string{"class", "id"} {
+		v, _ := nodeAttr(n, name)
+		if v == "" {
+			continue
+		}
+		if negativeCandidates.MatchString(v) {
+			score -= 25
+		}
+		if positiveCandidates.MatchString(v) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// isUnlikelyCandidate returns true for elements which are unlikely to hold the
+// content of the page, such as navigation and sidebars.
+func isUnlikelyCandidate(n *nethtml.Node) bool {
+	switch n.DataAtom {
+	case atom.Body, atom.Article, atom.Main, atom.A:
+		return false
+	case atom.Nav, atom.Aside, atom.Footer, atom.Button, atom.Select, atom.Iframe:
+		return true
+	}
+	switch role, _ := nodeAttr(n, "role"); role {
+	case "navigation", "complementary", "banner", "contentinfo", "menu", "menubar", "dialog", "alertdialog":
+		return true
+	}
+	class, _ := nodeAttr(n, "class")
+	id, _ := nodeAttr(n, "id")
+	match := class + " " + id
+	return unlikelyCandidates.MatchString(match) && !maybeCandidates.MatchString(match) && !hasAncestor(n, atom.Table)
+}
+
+// isScorable returns true for the elements whose text is scored. Divs are
+// scored when they hold text directly rather than in block elements.
+func isScorable(n *nethtml.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td:
+		return true
+	case atom.Div:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == nethtml.ElementNode && (isMarkdownBlock(c) || c.DataAtom == atom.Img) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// linkDensity returns the fraction of the text of the element that's inside of links.
+func linkDensity(n *nethtml.Node) float64 {
+	total := len(collapseSpace(nodeText(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	var walk func(*nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type == nethtml.ElementNode && n.DataAtom == atom.A {
+			links += len(collapseSpace(nodeText(n)))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+// articleItem returns the first JSON-LD item of the page describing an
+// article, or nil.
+func articleItem(bow *Browser) *Item {
+	items, _ := bow.jsonLD()
+	for _, item := range items {
+		for _, t := range item.Types {
+			name := typeName(t)
+			if strings.HasSuffix(name, "Article") || strings.HasSuffix(name, "Posting") || name == "Report" {
+				return item
+			}
+		}
+	}
+	return nil
+}
+
+// itemString returns the string value of the item property. Nested items
+// are represented by their name or url.
+func itemString(item *Item, prop string) string {
+	if item == nil {
+		return ""
+	}
+	if s := item.Get(prop); s != "" {
+		return s
+	}
+	if nested := item.Item(prop); nested != nil {
+		return firstString(nested.Get("name"), nested.Get("url"))
+	}
+	return ""
+}
+
+// articleByline returns the text of the first short element marked as the
+// author of the page.
+func articleByline(bow *Browser) string {
+	byline := ""
+	bow.Find(`[rel="author"],[itemprop~="author"],[class],[id]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if s.Is("body,html,meta") || isHiddenElement(s.Get(0)) {
+			return true
+		}
+		if !s.Is(`[rel="author"],[itemprop~="author"]`) {
+			class, _ := s.Attr("class")
+			id, _ := s.Attr("id")
+			if !bylineCandidates.MatchString(class + " " + id) {
+				return true
+			}
+		}
+		text := collapseSpace(s.Text())
+		if text == "" || len(text) > 100 {
+			return true
+		}
+		if len(text) > 3 && strings.EqualFold(text[:3], "by ") {
+			text = strings.TrimSpace(text[3:])
+		}
+		byline = text
+		return false
+	})
+	return byline
+}
+
+// metaContent returns the content of the first meta tag matching the selector.
+func metaContent(bow *Browser, selector string) string {
+	return strings.TrimSpace(bow.Find(selector).First().AttrOr("content", ""))
+}
+
+// cleanTitle removes the name of the site from a page title, such as
+// "Catching Waves | Surf Weekly". The title is returned unchanged when the
+// remaining text would be too short to be a headline.
+func cleanTitle(title string) string {
+	locs := titleSeparators.FindAllStringIndex(title, -1)
+	if len(locs) == 0 {
+		return title
+	}
+	cleaned := strings.TrimSpace(title[:locs[len(locs)-1][0]])
+	if len(strings.Fields(cleaned)) < 2 {
+		cleaned = strings.TrimSpace(title[locs[0][1]:])
+		if len(locs) > 1 || len(strings.Fields(cleaned)) < 2 {
+			return title
+		}
+	}
+	return cleaned
+}
+
+// articleTimeLayouts are the layouts tried when parsing published dates.
+var articleTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+// parseArticleTime parses a published date.
+func parseArticleTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range articleTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstValue returns the first string in the list, or an empty string.
+func firstValue(vals []string) string {
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// firstString returns the first string which isn't empty.
+func firstString(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/headzoo/ut"
+)
+
+var htmlArticle = `<!doctype html>
+<html>
+	<head>
+		<title>Catching Waves | Surf Weekly</title>
+		<meta property="article:published_time" content="2017-03-18T09:30:00Z" />
+	</head>
+	<body>
+		<div id="header"><a href="/">Surf Weekly</a> <a href="/news">News</a></div>
+		<ul class="menu"><li><a href="/boards">Boards</a></li><li><a href="/wax">Wax</a></li></ul>
+		<div class="main">
+			<div class="post-body">
+				<h1>Catching Waves</h1>
+				<p class="byline">By Sean Hart</p>
+				<p>Catching a wave takes practice, patience, and a good board. Paddle hard, keep your weight forward, and pop up as the wave lifts the tail.</p>
+				<img src="/img/wave.jpg" alt="A wave">
+				<p>Most beginners stand up too late, so the wave passes them by. Start your pop up a little earlier than feels natural, and you will catch more waves.</p>
+				<p>Once you are up, look where you want to go, bend your knees, and enjoy the ride back to the beach.</p>
+			</div>
+		</div>
+		<div class="sidebar">
+			<p>Subscribe to our newsletter for more tips, tricks, and the latest surf reports from around the world.</p>
+		</div>
+		<div id="comments">
+			<p>Great article, thanks for the tips, I will try them out this weekend at the beach!</p>
+		</div>
+	</body>
+</html>`
+
+func TestArticle(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.Write([]byte("<html><body><nav><a href=\"/\">Home</a></nav></body></html>"))
+			return
+		}
+		w.Write([]byte(htmlArticle))
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL + "/news/waves")
+	ut.AssertNil(err)
+
+	article, err := bow.Article()
+	ut.AssertNil(err)
+	ut.AssertEquals("Catching Waves", article.Title)
+	ut.AssertEquals("Sean Hart", article.Byline)
+	ut.AssertEquals(time.Date(2017, 3, 18, 9, 30, 0, 0, time.UTC), article.Published)
+	ut.AssertEquals(ts.URL+"/img/wave.jpg", article.Image.String())
+	ut.AssertTrue(article.Content.Is(".post-body"))
+
+	text := PlainText(article.Content)
+	ut.AssertTrue(strings.Contains(text, "Most beginners stand up too late"))
+	ut.AssertFalse(strings.Contains(text, "newsletter"))
+	ut.AssertFalse(strings.Contains(text, "Great article"))
+	ut.AssertFalse(strings.Contains(text, "Boards"))
+
+	err = bow.Open(ts.URL + "/empty")
+	ut.AssertNil(err)
+	_, err = bow.Article()
+	ut.AssertNotNil(err)
+}
+
+func TestCleanTitle(t *testing.T) {
+	ut.Run(t)
+	ut.AssertEquals("Catching Waves", cleanTitle("Catching Waves | Surf Weekly"))
+	ut.AssertEquals("Catching Waves", cleanTitle("Surf - Catching Waves"))
+	ut.AssertEquals("Surf: Home", cleanTitle("Surf: Home"))
+	ut.AssertEquals("Catching Waves", cleanTitle("Catching Waves"))
+}
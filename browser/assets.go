@@ -100,15 +100,25 @@ type Downloadable interface {
 // DownloadableAsset is an asset that may be downloaded.
 type DownloadableAsset struct {
 	Asset
+
+	// bow is the browser which found the asset, and which downloads it.
+	bow *Browser
 }
 
 // Download writes the asset to the given io.Writer type.
 func (at *DownloadableAsset) Download(out io.Writer) (int64, error) {
+	if at.bow != nil {
+		return at.bow.DownloadAsset(at, out)
+	}
 	return DownloadAsset(at, out)
 }
 
 // DownloadAsync downloads the asset asynchronously.
 func (at *DownloadableAsset) DownloadAsync(out io.Writer, ch AsyncDownloadChannel) {
+	if at.bow != nil {
+		at.bow.DownloadAssetAsync(at, out, ch)
+		return
+	}
 	DownloadAssetAsync(at, out, ch)
 }
 
@@ -220,9 +230,67 @@ func DownloadAsset(asset Downloadable, out io.Writer) (int64, error) {
 // DownloadAssetAsync downloads an asset asynchronously and notifies the given channel
 // when the download is complete.
 func DownloadAssetAsync(asset Downloadable, out io.Writer, c AsyncDownloadChannel) {
+	downloadAsync(asset, out, c, func(out io.Writer) (int64, error) {
+		return DownloadAsset(asset, out)
+	})
+}
+
+// DownloadAsset copies the asset to the given writer. Like the DownloadAsset
+// function, the request is sent without the headers and cookies of the
+// browser, and up to 10 redirects are followed whatever the attributes and
+// redirect policy of the browser, but it's sent with the transport of the
+// browser and traced with its tracer. The assets found by the browser, such
+// as the ones returned by Images, are downloaded this way.
+func (bow *Browser) DownloadAsset(asset Downloadable, out io.Writer) (int64, error) {
+	return bow.assetDownload(asset)(out)
+}
+
+// DownloadAssetAsync downloads the asset the same way as DownloadAsset, and
+// notifies the given channel when the download is complete.
+func (bow *Browser) DownloadAssetAsync(asset Downloadable, out io.Writer, c AsyncDownloadChannel) {
+	downloadAsync(asset, out, c, bow.assetDownload(asset))
+}
+
+// assetDownload prepares the request for the asset, and returns the function
+// which downloads it. The browser is only used to prepare the request, so the
+// download may run on another goroutine.
+func (bow *Browser) assetDownload(asset Downloadable) func(io.Writer) (int64, error) {
+	u := asset.Url().String()
+	ctx, span, end := startSpan(bow.tracer, bow.traceContext(), "surf.DownloadAsset",
+		map[string]interface{}{"url.full": u, "http.request.method": "GET"})
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		end(err)
+		return func(io.Writer) (int64, error) { return 0, err }
+	}
+	if bow.tracer != nil {
+		bow.tracer.Inject(ctx, req.Header)
+	}
+	if bow.client == nil {
+		bow.client = bow.buildClient()
+	}
+	client := &http.Client{Transport: bow.client.Transport}
+
+	return func(out io.Writer) (n int64, err error) {
+		defer func() { end(err) }()
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		if span != nil {
+			setResponseAttributes(span, resp)
+		}
+		return io.Copy(out, resp.Body)
+	}
+}
+
+// downloadAsync runs the download on another goroutine, and sends the results
+// to the channel when it's complete.
+func downloadAsync(asset Downloadable, out io.Writer, c AsyncDownloadChannel, download func(io.Writer) (int64, error)) {
 	go func() {
 		results := &AsyncDownloadResult{Asset: asset, Writer: out}
-		size, err := DownloadAsset(asset, out)
+		size, err := download(out)
 		if err != nil {
 			results.Error = err
 		} else {
@@ -2,22 +2,21 @@ package browser
 
 import (
 	"bytes"
-	"compress/flate"
-	"compress/gzip"
-	"fmt"
+	"context"
 	"io"
-	"io/ioutil"
-	"mime/multipart"
 	"net/http"
-	"net/http/httputil"
+	"net/http/httptrace"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/headzoo/surf/errors"
 	"github.com/headzoo/surf/jar"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+
+	nethtml "golang.org/x/net/html"
 )
 
 // Attribute represents a Browser capability.
@@ -26,24 +25,31 @@ type Attribute int
 // AttributeMap represents a map of Attribute values.
 type AttributeMap map[Attribute]bool
 
-// File represents a input type file, that includes the fileName and a io.reader
-type File struct {
-	fileName string
-	data     io.Reader
-}
-
-// FileSet represents a map of files used to port multipart
-type FileSet map[string]*File
-
 const (
 	// SendReferer instructs a Browser to send the Referer header.
 	SendReferer Attribute = iota
 
-	// MetaRefreshHandling instructs a Browser to handle the refresh meta tag.
+	// MetaRefreshHandling instructs a Browser to handle the refresh meta tag
+	// and the Refresh header.
 	MetaRefreshHandling
 
 	// FollowRedirects instructs a Browser to follow Location headers.
 	FollowRedirects
+
+	// JavaScript instructs a Browser to run the scripts in pages.
+	JavaScript
+
+	// SynchronousRefresh instructs a Browser to follow page refreshes before
+	// returning from Open, instead of in the background.
+	SynchronousRefresh
+
+	// StatusErrors instructs a Browser to return an error when a page is
+	// loaded with a 4xx or 5xx status.
+	StatusErrors
+
+	// LogBodies instructs a Browser to include the bodies of requests and
+	// responses in the events sent to its logger.
+	LogBodies
 )
 
 // InitialAssetsSliceSize is the initial size when allocating a slice of page
@@ -210,14 +216,52 @@ type Browser struct {
 	// attributes is the set browser attributes.
 	attributes AttributeMap
 
-	// refresh is a timer used to meta refresh pages.
-	refresh *time.Timer
+	// refresh holds the refresh of the page which is followed in the
+	// background.
+	refresh *refreshState
+
+	// refreshDepth counts the refreshes followed in a row to load the page
+	// which is loading.
+	refreshDepth int
 
 	// relativeUrl makes from <base> or page url
 	relativeUrl *url.URL
 
-	// body of the current page.
-	body []byte
+	// uploadProgress is called as multipart bodies are sent.
+	uploadProgress UploadProgressFunc
+
+	// redirectPolicy decides which redirects are followed.
+	redirectPolicy RedirectPolicy
+
+	// logger receives the events of the browser.
+	logger Logger
+
+	// metrics receives the measurements of the pages loaded by the browser.
+	metrics Metrics
+
+	// tracer traces the operations of the browser.
+	tracer Tracer
+
+	// traceParent holds the parent span of the spans started by the browser.
+	traceParent context.Context
+
+	// traceCtx and span are the context and span of the running operation.
+	traceCtx context.Context
+	span     Span
+
+	// encoding is used to decode pages instead of the detected encoding when
+	// it's not nil.
+	encoding encoding.Encoding
+
+	// js runs the scripts of the current page when the JavaScript attribute
+	// is set.
+	js *jsRuntime
+
+	// jsNavigations counts the pages loaded in a row by scripts.
+	jsNavigations int
+
+	// jsStorage holds the localStorage and sessionStorage items of each origin.
+	jsStorage map[string]map[string]string
 }
 
 // buildClient instanciates the *http.Client used by the browser
@@ -229,6 +273,16 @@ func (bow *Browser) buildClient() *http.Client {
 
 // Open requests the given URL using the GET method.
 func (bow *Browser) Open(u string) error {
+	defer bow.lockNavigation()()
+	return bow.open(u)
+}
+
+// open requests the given URL using the GET method, once the navigation lock
+// is held.
+func (bow *Browser) open(u string) (err error) {
+	end := bow.traceOperation("surf.Open", map[string]interface{}{"url.full": u, "http.request.method": "GET"})
+	defer func() { end(err) }()
+
 	ur, err := url.Parse(u)
 	if err != nil {
 		return err
@@ -238,6 +292,7 @@ func (bow *Browser) Open(u string) error {
 
 // Head requests the given URL using the HEAD method.
 func (bow *Browser) Head(u string) error {
+	defer bow.lockNavigation()()
 	ur, err := url.Parse(u)
 	if err != nil {
 		return err
@@ -247,6 +302,11 @@ func (bow *Browser) Head(u string) error {
 
 // OpenForm appends the data values to the given URL and sends a GET request.
 func (bow *Browser) OpenForm(u string, data url.Values) error {
+	return bow.OpenFormValues(u, NewFormValues(data))
+}
+
+// OpenFormValues appends the ordered data values to the given URL and sends a GET request.
+func (bow *Browser) OpenFormValues(u string, data FormValues) error {
 	ul, err := url.Parse(u)
 	if err != nil {
 		return err
@@ -267,6 +327,13 @@ func (bow *Browser) OpenBookmark(name string) error {
 
 // Post requests the given URL using the POST method.
 func (bow *Browser) Post(u string, contentType string, body io.Reader) error {
+	defer bow.lockNavigation()()
+	return bow.post(u, contentType, body)
+}
+
+// post requests the given URL using the POST method, once the navigation lock
+// is held.
+func (bow *Browser) post(u string, contentType string, body io.Reader) error {
 	ur, err := url.Parse(u)
 	if err != nil {
 		return err
@@ -276,37 +343,46 @@ func (bow *Browser) Post(u string, contentType string, body io.Reader) error {
 
 // PostForm requests the given URL using the POST method with the given data.
 func (bow *Browser) PostForm(u string, data url.Values) error {
+	return bow.PostFormValues(u, NewFormValues(data))
+}
+
+// PostFormValues requests the given URL using the POST method with the given ordered data.
+func (bow *Browser) PostFormValues(u string, data FormValues) error {
 	return bow.Post(u, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 }
 
 // PostMultipart requests the given URL using the POST method with the given data using multipart/form-data format.
 func (bow *Browser) PostMultipart(u string, fields url.Values, files FileSet) error {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	for k, vs := range fields {
-		for _, v := range vs {
-			writer.WriteField(k, v)
+	multi := make(MultiFileSet, len(files))
+	for name, file := range files {
+		if file != nil {
+			multi.Add(name, file)
 		}
 	}
-	for k, file := range files {
-		fw, err := writer.CreateFormFile(k, file.fileName)
-		if err != nil {
-			return err
-		}
-		if file.data != nil {
-			_, err = io.Copy(fw, file.data)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	err := writer.Close()
+	return bow.PostMultipartValues(u, NewFormValues(fields), multi)
+}
+
+// PostMultipartValues requests the given URL using the POST method with the given data using
+// multipart/form-data format. The fields are written in the order given, followed by the
+// files sorted by field name. Names may hold several files, and a name without files is
+// sent the way browsers send an input type file without a file.
+//
+// The body is streamed to the server as it's sent, so files are never read into memory.
+// The Content-Length header is sent when the size of every file is known in advance.
+func (bow *Browser) PostMultipartValues(u string, fields FormValues, files MultiFileSet) error {
+	defer bow.lockNavigation()()
+	return bow.postMultipart(u, multipartParts(fields, files))
+}
+
+// postMultipart requests the given URL using the POST method with the given parts using
+// multipart/form-data format. The parts are written in the order given.
+func (bow *Browser) postMultipart(u string, parts []multipartPart) error {
+	body, contentType, err := newMultipartBody(parts)
 	if err != nil {
 		return err
-
 	}
-	return bow.Post(u, writer.FormDataContentType(), body)
+	body.progress = bow.uploadProgress
+	return bow.post(u, contentType, body)
 }
 
 // Back loads the previously requested page.
@@ -314,6 +390,7 @@ func (bow *Browser) PostMultipart(u string, fields url.Values, files FileSet) er
 // Returns a boolean value indicating whether a previous page existed, and was
 // successfully loaded.
 func (bow *Browser) Back() bool {
+	defer bow.lockNavigation()()
 	if bow.history.Len() > 1 {
 		bow.state = bow.history.Pop()
 		return true
@@ -323,6 +400,13 @@ func (bow *Browser) Back() bool {
 
 // Reload duplicates the last successful request.
 func (bow *Browser) Reload() error {
+	defer bow.lockNavigation()()
+	return bow.reload()
+}
+
+// reload duplicates the last successful request, once the navigation lock is
+// held.
+func (bow *Browser) reload() error {
 	if bow.state.Request != nil {
 		return bow.httpRequest(bow.state.Request)
 	}
@@ -334,46 +418,151 @@ func (bow *Browser) Bookmark(name string) error {
 	return bow.bookmarks.Save(name, bow.ResolveUrl(bow.Url()).String())
 }
 
-// Click clicks on the page element matched by the given expression.
+// Click clicks on the first page element matched by the given expression.
 //
-// Currently this is only useful for click on links, which will cause the browser
-// to load the page pointed at by the link. Future versions of Surf may support
-// JavaScript and clicking on elements will fire the click event.
+// Clicking a link or an image map area loads the page it points at. Clicking
+// a submit button, including button elements without a type, submits the form
+// the button belongs to along with the name and value of the button. Clicking
+// an image input submits the form along with the coordinates of the click,
+// which are always 0,0, as name.x and name.y. Clicking any other element
+// inside of a link follows the link.
+//
+// When the JavaScript attribute is set the click event is fired first, and
+// nothing else is done when a listener cancels the event. Submitting a form
+// fires the submit event, and the page the listeners navigate to is loaded.
 func (bow *Browser) Click(expr string) error {
-	sel := bow.Find(expr)
+	defer bow.lockNavigation()()
+	if err := bow.requireHTML("click elements in"); err != nil {
+		return err
+	}
+	return bow.click(bow.Find(expr), expr)
+}
+
+// ClickLink clicks on the first link with the given text. Links with exactly
+// the same text are preferred, and otherwise the first link containing the
+// text, ignoring case, is clicked. Runs of white space in the text of the
+// links are collapsed. The alt text of images is used for links without text.
+func (bow *Browser) ClickLink(text string) error {
+	defer bow.lockNavigation()()
+	if err := bow.requireHTML("click links in"); err != nil {
+		return err
+	}
+	want := collapseSpace(text)
+	if want == "" {
+		return errors.NewLinkNotFound("Link text must not be empty.").With(errors.Fields{Expr: text})
+	}
+	var exact, partial *goquery.Selection
+	bow.Find("a[href],area[href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		lt := linkText(s)
+		if lt == want {
+			exact = s
+			return false
+		}
+		if partial == nil && strings.Contains(strings.ToLower(lt), strings.ToLower(want)) {
+			partial = s
+		}
+		return true
+	})
+	if exact == nil {
+		exact = partial
+	}
+	if exact == nil {
+		return errors.NewLinkNotFound(
+			"No link found with the text '%s'.", text).With(errors.Fields{Expr: text})
+	}
+	return bow.click(exact, text)
+}
+
+// click clicks on the first element in the selection, which was matched by expr.
+func (bow *Browser) click(sel *goquery.Selection, expr string) (err error) {
+	end := bow.traceOperation("surf.Click", map[string]interface{}{"surf.expr": expr})
+	defer func() { end(err) }()
+
 	if sel.Length() == 0 {
 		return errors.NewElementNotFound(
-			"Element not found matching expr '%s'.", expr)
+			"Element not found matching expr '%s'.", expr).With(errors.Fields{Expr: expr})
 	}
-	if !sel.Is("a") {
-		return errors.NewElementNotFound(
-			"Expr '%s' must match an anchor tag.", expr)
+	sel = sel.First()
+
+	if rt := bow.scripts(); rt != nil {
+		if handled, err := rt.clickElement(sel.Get(0)); handled {
+			return err
+		}
 	}
 
-	href, err := bow.attrToResolvedUrl("href", sel)
-	if err != nil {
-		return err
+	if sel.Is("a,area") {
+		href, err := bow.attrToResolvedUrl("href", sel)
+		if err != nil {
+			return err
+		}
+		return bow.httpGET(href, bow.Url())
 	}
 
-	return bow.httpGET(href, bow.Url())
+	if t := controlType(sel); sel.Is("button,input") && (t == "submit" || t == "image") {
+		n := sel.Get(0)
+		if isControlDisabled(n) {
+			return errors.NewInvalidFormValue(
+				"Button matching expr '%s' is disabled.", expr).With(errors.Fields{Expr: expr})
+		}
+		ids := make(map[string]*nethtml.Node)
+		indexIds(bow.state.Dom.Get(0), ids)
+		owner := formOwner(n, ids)
+		if owner == nil {
+			return errors.NewElementNotFound(
+				"Button matching expr '%s' does not belong to a form.", expr).With(errors.Fields{Expr: expr})
+		}
+		form := NewForm(bow, bow.state.Dom.FindNodes(owner))
+		return form.submitWith(sel)
+	}
+
+	if link := sel.Closest("a[href]"); link.Length() > 0 {
+		return bow.click(link, expr)
+	}
+	return errors.NewElementNotFound(
+		"Expr '%s' does not match a link or a submit button.", expr).With(errors.Fields{Expr: expr})
+}
+
+// linkText returns the text of a link, which is the alt text of its images
+// when the link has no text of its own.
+func linkText(s *goquery.Selection) string {
+	text := collapseSpace(s.Text())
+	if text != "" {
+		return text
+	}
+	if alt, ok := s.Attr("alt"); ok {
+		return collapseSpace(alt)
+	}
+	var alts []string
+	s.Find("img[alt]").Each(func(_ int, img *goquery.Selection) {
+		alts = append(alts, img.AttrOr("alt", ""))
+	})
+	return collapseSpace(strings.Join(alts, " "))
 }
 
 // Form returns the form in the current page that matches the given expr.
 func (bow *Browser) Form(expr string) (Submittable, error) {
-	sel := bow.Find(expr)
+	if err := bow.requireHTML("find forms in"); err != nil {
+		return nil, err
+	}
+	return bow.form(bow.Find(expr), expr)
+}
+
+// form returns the form in the selection, which was matched by expr.
+func (bow *Browser) form(sel *goquery.Selection, expr string) (Submittable, error) {
 	if sel.Length() == 0 {
 		return nil, errors.NewElementNotFound(
-			"Form not found matching expr '%s'.", expr)
+			"Form not found matching expr '%s'.", expr).With(errors.Fields{Expr: expr})
 	}
 	if !sel.Is("form") {
 		return nil, errors.NewElementNotFound(
-			"Expr '%s' does not match a form tag.", expr)
+			"Expr '%s' does not match a form tag.", expr).With(errors.Fields{Expr: expr})
 	}
 
 	return NewForm(bow, sel), nil
 }
 
-// Forms returns an array of every form in the page.
+// Forms returns an array of every form in the page. Pages which aren't HTML
+// have no forms, use QueryForms to get an error for them instead.
 func (bow *Browser) Forms() []Submittable {
 	sel := bow.Find("form")
 	len := sel.Length()
@@ -381,13 +570,22 @@ func (bow *Browser) Forms() []Submittable {
 		return nil
 	}
 
-	forms := make([]Submittable, len)
+	forms := make([]Submittable, 0, len)
 	sel.Each(func(_ int, s *goquery.Selection) {
 		forms = append(forms, NewForm(bow, s))
 	})
 	return forms
 }
 
+// QueryForms returns an array of every form in the page. An UnsupportedMediaType
+// error is returned when the page isn't HTML.
+func (bow *Browser) QueryForms() ([]Submittable, error) {
+	if err := bow.requireHTML("find forms in"); err != nil {
+		return nil, err
+	}
+	return bow.Forms(), nil
+}
+
 // Links returns an array of every link found in the page.
 func (bow *Browser) Links() []*Link {
 	links := make([]*Link, 0, InitialAssetsSliceSize)
@@ -411,12 +609,14 @@ func (bow *Browser) Images() []*Image {
 	bow.Find("img").Each(func(_ int, s *goquery.Selection) {
 		src, err := bow.attrToResolvedUrl("src", s)
 		if err == nil {
-			images = append(images, NewImageAsset(
+			img := NewImageAsset(
 				src,
 				bow.attrOrDefault("id", "", s),
 				bow.attrOrDefault("alt", "", s),
 				bow.attrOrDefault("title", "", s),
-			))
+			)
+			img.bow = bow
+			images = append(images, img)
 		}
 	})
 
@@ -431,12 +631,14 @@ func (bow *Browser) Stylesheets() []*Stylesheet {
 		if ok && rel == "stylesheet" {
 			href, err := bow.attrToResolvedUrl("href", s)
 			if err == nil {
-				stylesheets = append(stylesheets, NewStylesheetAsset(
+				css := NewStylesheetAsset(
 					href,
 					bow.attrOrDefault("id", "", s),
 					bow.attrOrDefault("media", "all", s),
 					bow.attrOrDefault("type", "text/css", s),
-				))
+				)
+				css.bow = bow
+				stylesheets = append(stylesheets, css)
 			}
 		}
 	})
@@ -450,11 +652,13 @@ func (bow *Browser) Scripts() []*Script {
 	bow.Find("script").Each(func(_ int, s *goquery.Selection) {
 		src, err := bow.attrToResolvedUrl("src", s)
 		if err == nil {
-			scripts = append(scripts, NewScriptAsset(
+			script := NewScriptAsset(
 				src,
 				bow.attrOrDefault("id", "", s),
 				bow.attrOrDefault("type", "text/javascript", s),
-			))
+			)
+			script.bow = bow
+			scripts = append(scripts, script)
 		}
 	})
 
@@ -552,6 +756,36 @@ func (bow *Browser) SetTransport(rt http.RoundTripper) {
 	bow.client.Transport = rt
 }
 
+// SetUploadProgress sets a function which is called as multipart request bodies,
+// such as forms with files, are sent. Passing nil removes the function.
+func (bow *Browser) SetUploadProgress(fn UploadProgressFunc) {
+	bow.uploadProgress = fn
+}
+
+// SetRedirectPolicy sets the rules for following redirects when the
+// FollowRedirects attribute is set. The zero RedirectPolicy follows every
+// redirect.
+func (bow *Browser) SetRedirectPolicy(p RedirectPolicy) {
+	bow.redirectPolicy = p
+}
+
+// SetEncoding forces the character encoding used to decode pages, such as
+// "shift_jis" or "windows-1251", instead of the encoding declared by the page.
+// Labels are the names used by web browsers, and passing an empty label
+// restores detection.
+func (bow *Browser) SetEncoding(label string) error {
+	if label == "" {
+		bow.encoding = nil
+		return nil
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return errors.New("Unknown character encoding '%s'.", label).With(errors.Fields{Err: err})
+	}
+	bow.encoding = enc
+	return nil
+}
+
 // AddRequestHeader sets a header the browser sends with each request.
 func (bow *Browser) AddRequestHeader(name, value string) {
 	bow.headers.Set(name, value)
@@ -586,12 +820,20 @@ func (bow *Browser) RelativeUrl() *url.URL {
 	return bow.relativeUrl
 }
 
-// Download writes the contents of the document to the given writer.
+// Download writes the contents of the document to the given writer. The bytes
+// are written as they were received, before they were decoded to UTF-8.
 func (bow *Browser) Download(o io.Writer) (int64, error) {
-	buff := bytes.NewBuffer(bow.body)
+	buff := bytes.NewBuffer(bow.state.Body)
 	return io.Copy(o, buff)
 }
 
+// Charset returns the name of the character encoding the page was decoded
+// from, such as "utf-8" or "shift_jis". Pages are always parsed as UTF-8,
+// whatever encoding they were sent in.
+func (bow *Browser) Charset() string {
+	return bow.state.Charset
+}
+
 // Url returns the page URL as a string.
 func (bow *Browser) Url() *url.URL {
 	if bow.state.Response == nil {
@@ -621,8 +863,12 @@ func (bow *Browser) ResponseHeaders() http.Header {
 	return bow.state.Response.Header
 }
 
-// Body returns the page body as a string of html.
+// Body returns the page body as a string of html. The content of pages which
+// aren't HTML, such as text/plain and JSON pages, is returned as it was received.
 func (bow *Browser) Body() string {
+	if !isHTMLMediaType(bow.MediaType()) {
+		return string(bow.state.Body)
+	}
 	body, _ := bow.state.Dom.Find("body").Html()
 	return body
 }
@@ -632,14 +878,25 @@ func (bow *Browser) Dom() *goquery.Selection {
 	return bow.state.Dom.First()
 }
 
-// Find returns the dom selections matching the given expression.
+// Find returns the dom selections matching the given expression. Nothing is
+// found in pages which aren't HTML, use Query to get an error for them instead.
 func (bow *Browser) Find(expr string) *goquery.Selection {
 	return bow.state.Dom.Find(expr)
 }
 
+// Query returns the dom selections matching the given expression. An
+// UnsupportedMediaType error is returned when the page isn't HTML.
+func (bow *Browser) Query(expr string) (*goquery.Selection, error) {
+	if err := bow.requireHTML("find elements in"); err != nil {
+		return nil, err
+	}
+	return bow.Find(expr), nil
+}
+
 func (bow *Browser) NewTab() (b *Browser) {
 	b = &Browser{}
 	*b = *bow
+	b.refresh = nil
 
 	return b
 }
@@ -651,20 +908,27 @@ func (bow *Browser) buildRequest(method, url string, ref *url.URL, body io.Reade
 	if err != nil {
 		return nil, err
 	}
+	if sb, ok := body.(interface{ Size() int64 }); ok && sb.Size() >= 0 {
+		req.ContentLength = sb.Size()
+	}
+	if gb, ok := body.(interface {
+		getBody() func() (io.ReadCloser, error)
+	}); ok {
+		req.GetBody = gb.getBody()
+	}
 	req.Header = copyHeaders(bow.headers)
 
 	if host := req.Header.Get("Host"); host != "" {
 		req.Host = host
 	}
 	req.Header.Set("User-Agent", bow.userAgent)
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", AcceptEncoding)
+	}
 	if bow.attributes[SendReferer] && ref != nil {
 		req.Header.Set("Referer", ref.String())
 	}
-	if os.Getenv("SURF_DEBUG_HEADERS") != "" {
-		d, _ := httputil.DumpRequest(req, false)
-		fmt.Fprintln(os.Stderr, "===== [DUMP] =====\n", string(d))
-	}
-
+	bow.injectTrace(req)
 	return req, nil
 }
 
@@ -720,69 +984,99 @@ func (bow *Browser) httpRequest(req *http.Request) error {
 		bow.client = bow.buildClient()
 	}
 	bow.preSend()
+	timer := newRequestTimer()
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timer.trace()))
+	bow.logRequest(req)
 	resp, err := bow.client.Do(req)
 	if err != nil {
+		bow.Logger().Error("request failed", "method", req.Method, "url", req.URL.String(), "error", err)
+		bow.recordError(req)
+		if resp != nil {
+			// The client only returns a response with an error when a
+			// redirect was refused.
+			return newRedirectError(resp, err)
+		}
 		return err
 	}
 	defer resp.Body.Close()
+	bow.traceResponse(resp)
 
-	var reader io.Reader
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			return err
-		}
-	case "deflate":
-		reader = flate.NewReader(resp.Body)
-
-	default:
-		reader = resp.Body
-	}
-
-	bow.body, err = ioutil.ReadAll(reader)
+	body := &countingReader{ReadCloser: resp.Body}
+	resp.Body = body
+	raw, err := readBody(resp)
 	if err != nil {
 		return err
 	}
-
-	buff := bytes.NewBuffer(bow.body)
-	dom, err := goquery.NewDocumentFromReader(buff)
-	if err != nil {
-		return err
+	if enc := unsupportedEncoding(contentEncodings(resp.Header)); enc != "" {
+		bow.Logger().Warn("unsupported content encoding", "url", resp.Request.URL.String(), "encoding", enc)
+	}
+	timing := timer.done(body.n, int64(len(raw)))
+
+	// Only HTML pages are parsed, and other pages get an empty DOM.
+	mediaType := responseMediaType(resp, raw)
+	dom, charset := emptyDocument(), ""
+	if isHTMLMediaType(mediaType) {
+		enc, name := detectCharset(raw, resp.Header.Get("Content-Type"))
+		if bow.encoding != nil {
+			enc, name = bow.encoding, charsetName(bow.encoding)
+		}
+		body, err := decodeCharset(raw, enc, name)
+		if err != nil {
+			return err
+		}
+		dom, err = goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		charset = name
 	}
 
 	bow.history.Push(bow.state)
 	bow.state = jar.NewHistoryState(req, resp, dom)
+	bow.state.MediaType = mediaType
+	bow.state.Charset = charset
+	bow.state.Body = raw
+	bow.state.Redirects = redirectChain(resp)
+	bow.state.Timing = timing
+	bow.logResponse(req, resp)
+	bow.recordMetrics(resp, timing)
 	bow.postSend()
 
-	return nil
+	if bow.attributes[StatusErrors] && resp.StatusCode >= 400 {
+		return statusError(bow.state)
+	}
+	if bow.attributes[JavaScript] && isHTMLMediaType(mediaType) && req.Method != "HEAD" {
+		state := bow.state
+		if err := bow.runScripts(); err != nil || bow.state != state {
+			return err
+		}
+	}
+	return bow.handleRefresh()
+}
+
+// statusError returns the error for a page loaded with an error status,
+// which is a PageNotFound for the 404 status and a HTTPStatus for the others.
+// The page is loaded so it can be read, but its scripts aren't run and its
+// refresh isn't followed.
+func statusError(state *jar.State) error {
+	resp := state.Response
+	fields := errors.Fields{URL: resp.Request.URL.String()}
+	if resp.StatusCode == 404 {
+		return errors.NewPageNotFoundStatus(state,
+			"'%s' returned %s.", resp.Request.URL, resp.Status).With(fields)
+	}
+	return errors.NewHTTPStatus(resp.StatusCode, state,
+		"'%s' returned %s.", resp.Request.URL, resp.Status).With(fields)
 }
 
 // preSend sets browser state before sending a request.
 func (bow *Browser) preSend() {
-	if bow.refresh != nil {
-		bow.refresh.Stop()
-	}
+	bow.cancelRefresh()
 }
 
 // postSend sets browser state after sending a request.
 func (bow *Browser) postSend() {
-	if isContentTypeHtml(bow.state.Response) && bow.attributes[MetaRefreshHandling] {
-		sel := bow.Find("meta[http-equiv='refresh']")
-		if sel.Length() > 0 {
-			attr, ok := sel.Attr("content")
-			if ok {
-				dur, err := time.ParseDuration(attr + "s")
-				if err == nil {
-					bow.refresh = time.NewTimer(dur)
-					go func() {
-						<-bow.refresh.C
-						bow.Reload()
-					}()
-				}
-			}
-		}
-
+	if isHTMLMediaType(bow.MediaType()) {
 		baseTag := bow.Find("base[href]")
 		if baseTag.Length() > 0 {
 			if href, exists := baseTag.Attr("href"); exists {
@@ -797,13 +1091,20 @@ func (bow *Browser) postSend() {
 }
 
 // shouldRedirect is used as the value to http.Client.CheckRedirect.
-func (bow *Browser) shouldRedirect(req *http.Request, _ []*http.Request) error {
-	if bow.attributes[FollowRedirects] {
-		req.Header.Set("User-Agent", bow.userAgent)
-		return nil
+func (bow *Browser) shouldRedirect(req *http.Request, via []*http.Request) error {
+	if !bow.attributes[FollowRedirects] {
+		return errors.NewLocation(
+			"Redirects are disabled. Cannot follow '%s'.", req.URL.String()).
+			With(errors.Fields{URL: req.URL.String()})
 	}
-	return errors.NewLocation(
-		"Redirects are disabled. Cannot follow '%s'.", req.URL.String())
+	from, status := via[len(via)-1].URL.String(), req.Response.StatusCode
+	if err := bow.redirectPolicy.check(req, via); err != nil {
+		bow.Logger().Warn("redirect refused", "url", from, "location", req.URL.String(), "status", status, "error", err)
+		return err
+	}
+	bow.Logger().Info("redirect", "url", from, "location", req.URL.String(), "status", status)
+	req.Header.Set("User-Agent", bow.userAgent)
+	return nil
 }
 
 // attributeToUrl reads an attribute from an element and returns a url.
@@ -811,7 +1112,7 @@ func (bow *Browser) attrToResolvedUrl(name string, sel *goquery.Selection) (*url
 	src, ok := sel.Attr(name)
 	if !ok {
 		return nil, errors.NewAttributeNotFound(
-			"Attribute '%s' not found.", name)
+			"Attribute '%s' not found.", name).With(errors.Fields{Field: name})
 	}
 	ur, err := url.Parse(src)
 	if err != nil {
@@ -830,11 +1131,3 @@ func (bow *Browser) attrOrDefault(name, def string, sel *goquery.Selection) stri
 	return def
 }
 
-// isContentTypeHtml returns true when the given response sent the "text/html" content type.
-func isContentTypeHtml(res *http.Response) bool {
-	if res != nil {
-		ct := res.Header.Get("Content-Type")
-		return ct == "" || strings.Contains(ct, "text/html")
-	}
-	return false
-}
@@ -1,6 +1,7 @@
 package browser
 
 import (
+	stderrors "errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/headzoo/surf/agent"
+	"github.com/headzoo/surf/errors"
 	"github.com/headzoo/surf/jar"
 )
 
@@ -227,6 +229,14 @@ func TestLinksWithBaseTag(t *testing.T) {
 			io.WriteString(w, `<html><body>
 				<a href="page.html">Link</a>
 			</body></html>`)
+
+		case "/xhtml_base/":
+			w.Header().Set("Content-type", "application/xhtml+xml")
+			io.WriteString(w, `<html><head>
+			<base href="/other/">
+				</head><body>
+				<a href="page.html">Link</a>
+			</body></html>`)
 		}
 	}))
 	defer ts.Close()
@@ -262,4 +272,81 @@ func TestLinksWithBaseTag(t *testing.T) {
 	if links2[0].URL.String() != ts.URL + "/page.html" {
 		t.Fatal("Tag base not processed")
 	}
-}
\ No newline at end of file
+
+	// Behavior with base tag in an XHTML page
+	if err := b.Open(ts.URL + "/xhtml_base/"); err != nil {
+		t.Fatal(err)
+	}
+
+	links3 := b.Links()
+
+	if len(links3) != 1 {
+		t.Fatal("Error: not found link in document")
+	}
+
+	if links3[0].URL.String() != ts.URL + "/other/page.html" {
+		t.Fatal("Tag base not processed")
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte("<html><head><title>Found</title></head></html>"))
+		case "/broken":
+			http.Error(w, "Broken", 500)
+		default:
+			http.Error(w, "Missing", 404)
+		}
+	}))
+	defer ts.Close()
+
+	bow := newDefaultTestBrowser()
+	if err := bow.Open(ts.URL + "/missing"); err != nil {
+		t.Errorf("Expected no error without StatusErrors, got %s", err)
+	}
+
+	bow.SetAttribute(StatusErrors, true)
+	if err := bow.Open(ts.URL); err != nil {
+		t.Errorf("Expected no error for %s, got %s", ts.URL, err)
+	}
+
+	err := bow.Open(ts.URL + "/missing")
+	if !stderrors.Is(err, errors.PageNotFound{}) {
+		t.Errorf("Expected a PageNotFound error, got %#v", err)
+	}
+	if !stderrors.Is(err, errors.HTTPStatus{Fields: errors.Fields{StatusCode: 404}}) {
+		t.Errorf("Expected a 404 HTTPStatus error, got %#v", err)
+	}
+	if bow.StatusCode() != 404 {
+		t.Errorf("Expected the 404 page to be loaded, got %d", bow.StatusCode())
+	}
+
+	err = bow.Open(ts.URL + "/broken")
+	var status errors.HTTPStatus
+	if !stderrors.As(err, &status) || status.StatusCode != 500 {
+		t.Fatalf("Expected a 500 HTTPStatus error, got %#v", err)
+	}
+	if stderrors.Is(err, errors.PageNotFound{}) || stderrors.Is(err, errors.HTTPStatus{Fields: errors.Fields{StatusCode: 404}}) {
+		t.Errorf("Expected the 500 error not to match 404 errors")
+	}
+	if status.State.(*jar.State) != bow.State() {
+		t.Errorf("Expected the error to hold the page state")
+	}
+	if status.URL != ts.URL+"/broken" {
+		t.Errorf("Expected the error URL to be %s, got %s", ts.URL+"/broken", status.URL)
+	}
+
+	if err := bow.Open(ts.URL); err != nil {
+		t.Fatalf("Expected no error for %s, got %s", ts.URL, err)
+	}
+	err = bow.Click("#nothing")
+	var notFound errors.ElementNotFound
+	if !stderrors.As(err, &notFound) || notFound.Expr != "#nothing" {
+		t.Errorf("Expected an ElementNotFound error for #nothing, got %#v", err)
+	}
+	if !stderrors.Is(err, errors.ElementNotFound{}) || stderrors.Is(err, errors.PageNotFound{}) {
+		t.Errorf("Expected the error to only match ElementNotFound errors")
+	}
+}
@@ -0,0 +1,246 @@
+package browser
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/surf/jar"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	xunicode "golang.org/x/text/encoding/unicode"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// charsetPrescanSize is the number of bytes searched for a meta tag declaring
+// the page encoding, which is the same number web browsers search.
+const charsetPrescanSize = 1024
+
+// byteOrderMarks are the byte order marks which declare the page encoding.
+var byteOrderMarks = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte{0xef, 0xbb, 0xbf}, "utf-8"},
+	{[]byte{0xfe, 0xff}, "utf-16be"},
+	{[]byte{0xff, 0xfe}, "utf-16le"},
+}
+
+// detectCharset determines the encoding of an HTML page and returns the
+// encoding along with its name.
+//
+// The encoding is taken from the byte order mark, the charset parameter of
+// the Content-Type header, and the meta tags in the first 1024 bytes of the
+// page, in that order. Pages without a declared encoding are UTF-8 when
+// they're valid UTF-8, and windows-1252 otherwise, which is how web browsers
+// treat ISO-8859-1 and ASCII.
+func detectCharset(body []byte, contentType string) (encoding.Encoding, string) {
+	for _, b := range byteOrderMarks {
+		if bytes.HasPrefix(body, b.bom) {
+			return charset.Lookup(b.name)
+		}
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if enc, name := charset.Lookup(params["charset"]); enc != nil {
+			return enc, name
+		}
+	}
+	if enc, name := prescanCharset(body); enc != nil {
+		return enc, name
+	}
+	if utf8.Valid(body) {
+		return xunicode.UTF8, "utf-8"
+	}
+	return charmap.Windows1252, "windows-1252"
+}
+
+// prescanCharset returns the encoding declared by a meta charset or
+// http-equiv="Content-Type" tag near the start of the page, or nil.
+func prescanCharset(body []byte) (encoding.Encoding, string) {
+	if len(body) > charsetPrescanSize {
+		body = body[:charsetPrescanSize]
+	}
+	z := nethtml.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case nethtml.ErrorToken:
+			return nil, ""
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			tok := z.Token()
+			if tok.Data != "meta" {
+				continue
+			}
+			var label, httpEquiv, content string
+			for _, a := range tok.Attr {
+				switch strings.ToLower(a.Key) {
+				case "charset":
+					label = a.Val
+				case "http-equiv":
+					httpEquiv = a.Val
+				case "content":
+					content = a.Val
+				}
+			}
+			if label == "" && strings.EqualFold(strings.TrimSpace(httpEquiv), "content-type") {
+				if _, params, err := mime.ParseMediaType(content); err == nil {
+					label = params["charset"]
+				}
+			}
+			if enc, name := charset.Lookup(label); enc != nil {
+				// A page can't declare itself UTF-16 in a meta tag, since
+				// the tag could not have been read.
+				if strings.HasPrefix(name, "utf-16") {
+					return xunicode.UTF8, "utf-8"
+				}
+				return enc, name
+			}
+		}
+	}
+}
+
+// decodeCharset converts the page body from the encoding to UTF-8. The byte
+// order mark is removed.
+func decodeCharset(body []byte, enc encoding.Encoding, name string) ([]byte, error) {
+	if name != "utf-8" {
+		var err error
+		body, err = enc.NewDecoder().Bytes(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bytes.TrimPrefix(body, byteOrderMarks[0].bom), nil
+}
+
+// charsetName returns the name web browsers use for the encoding.
+func charsetName(enc encoding.Encoding) string {
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// pageCharset returns the encoding of the page in the state, which is used to
+// submit forms. Pages encoded with UTF-16 submit forms using UTF-8.
+func pageCharset(st *jar.State) encoding.Encoding {
+	if st == nil || st.Charset == "" || strings.HasPrefix(st.Charset, "utf-16") {
+		return xunicode.UTF8
+	}
+	enc, err := htmlindex.Get(st.Charset)
+	if err != nil {
+		return xunicode.UTF8
+	}
+	return enc
+}
+
+// formCharset returns the encoding used to submit the given form.
+//
+// The first label in the form accept-charset attribute which names a known
+// encoding is used, and the encoding of the page is used when there's no such
+// label.
+func formCharset(sel *goquery.Selection, page encoding.Encoding) encoding.Encoding {
+	if labels, ok := sel.Attr("accept-charset"); ok {
+		for _, label := range strings.FieldsFunc(labels, isCharsetSeparator) {
+			if enc, err := htmlindex.Get(label); err == nil {
+				return enc
+			}
+		}
+	}
+	return page
+}
+
+// isCharsetSeparator returns true for the runes which separate the labels in an
+// accept-charset attribute. Commas are not valid separators, but they are
+// common enough that web browsers accept them.
+func isCharsetSeparator(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+}
+
+// encodeFormValues converts the names and values of the form data set to the
+// given encoding, and normalizes line breaks to CRLF pairs the same way a web
+// browser does. Characters which can't be represented in the encoding are
+// replaced with HTML numeric character references.
+func encodeFormValues(values FormValues, enc encoding.Encoding) (FormValues, error) {
+	encoder := encoding.HTMLEscapeUnsupported(enc.NewEncoder())
+	encoded := make(FormValues, len(values))
+	for i, v := range values {
+		name, err := encoder.String(normalizeNewlines(v.Name))
+		if err != nil {
+			return nil, err
+		}
+		value, err := encoder.String(normalizeNewlines(v.Value))
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = FormValue{Name: name, Value: value}
+	}
+	return encoded, nil
+}
+
+// encodeTextPlain encodes the form data set using the text/plain enctype.
+func encodeTextPlain(values FormValues) string {
+	var buf strings.Builder
+	for _, v := range values {
+		buf.WriteString(v.Name)
+		buf.WriteByte('=')
+		buf.WriteString(v.Value)
+		buf.WriteString("\r\n")
+	}
+	return buf.String()
+}
+
+// normalizeNewlines replaces every lone CR and lone LF with a CRLF pair.
+func normalizeNewlines(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	s = strings.Replace(s, "\r", "\n", -1)
+	return strings.Replace(s, "\n", "\r\n", -1)
+}
+
+// directionality returns "rtl" or "ltr" to describe the text direction of a
+// form control, which is submitted as the value of its dirname attribute.
+func directionality(s *goquery.Selection, value string) string {
+	control := s.Get(0)
+	for n := control; n != nil; n = n.Parent {
+		dir, _ := nodeAttr(n, "dir")
+		switch strings.ToLower(dir) {
+		case "rtl":
+			return "rtl"
+		case "ltr":
+			return "ltr"
+		case "auto":
+			if n == control {
+				return autoDirection(value)
+			}
+		}
+	}
+	return "ltr"
+}
+
+// autoDirection returns the direction of the first strongly directional
+// character in the value, which defaults to "ltr".
+func autoDirection(value string) string {
+	for _, r := range value {
+		if isRTL(r) {
+			return "rtl"
+		}
+		if unicode.IsLetter(r) {
+			return "ltr"
+		}
+	}
+	return "ltr"
+}
+
+// isRTL returns true when the rune belongs to a right-to-left script.
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac,
+		unicode.Thaana, unicode.Nko, unicode.Samaritan, unicode.Mandaic)
+}
@@ -0,0 +1,113 @@
+package browser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/headzoo/ut"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+func TestBrowserCharset(t *testing.T) {
+	ut.Run(t)
+	encode := func(enc encoding.Encoding, s string) []byte {
+		b, err := enc.NewEncoder().Bytes([]byte(s))
+		ut.AssertNil(err)
+		return b
+	}
+	pages := map[string]struct {
+		contentType string
+		body        []byte
+	}{
+		"/header": {
+			"text/html; charset=Shift_JIS",
+			encode(japanese.ShiftJIS, "<html><head><title>日本語</title></head><body>こんにちは</body></html>"),
+		},
+		"/meta": {
+			"text/html",
+			encode(simplifiedchinese.GBK, `<html><head><meta charset="gbk"><title>中文</title></head></html>`),
+		},
+		"/http-equiv": {
+			"text/html",
+			encode(charmap.Windows1251, `<html><head><meta http-equiv="Content-Type" content="text/html; charset=windows-1251"><title>Привет</title></head></html>`),
+		},
+		"/latin1": {
+			"text/html",
+			encode(charmap.ISO8859_1, `<html><head><title>Café</title></head><body><form method="post"><input name="q" value="é"></form></body></html>`),
+		},
+		"/bom": {
+			"text/html; charset=iso-8859-1",
+			encode(xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM), "<html><head><title>Größe</title></head></html>"),
+		},
+		"/utf8": {
+			"text/html",
+			[]byte("<html><head><title>naïve</title></head></html>"),
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(body)
+			return
+		}
+		page := pages[r.URL.Path]
+		w.Header().Set("Content-Type", page.contentType)
+		w.Write(page.body)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	open := func(path string) {
+		err := bow.Open(ts.URL + path)
+		ut.AssertNil(err)
+	}
+
+	open("/header")
+	ut.AssertEquals("shift_jis", bow.Charset())
+	ut.AssertEquals("日本語", bow.Title())
+	ut.AssertEquals("こんにちは", bow.Find("body").Text())
+	var raw bytes.Buffer
+	_, err := bow.Download(&raw)
+	ut.AssertNil(err)
+	ut.AssertEquals(pages["/header"].body, raw.Bytes())
+
+	open("/meta")
+	ut.AssertEquals("gbk", bow.Charset())
+	ut.AssertEquals("中文", bow.Title())
+
+	open("/http-equiv")
+	ut.AssertEquals("windows-1251", bow.Charset())
+	ut.AssertEquals("Привет", bow.Title())
+
+	open("/latin1")
+	ut.AssertEquals("windows-1252", bow.Charset())
+	ut.AssertEquals("Café", bow.Title())
+	f, err := bow.Form("form")
+	ut.AssertNil(err)
+	ut.AssertNil(f.Submit())
+	ut.AssertEquals("q=%E9", string(bow.state.Body))
+
+	open("/bom")
+	ut.AssertEquals("utf-16le", bow.Charset())
+	ut.AssertEquals("Größe", bow.Title())
+
+	open("/utf8")
+	ut.AssertEquals("utf-8", bow.Charset())
+	ut.AssertEquals("naïve", bow.Title())
+
+	ut.AssertNil(bow.SetEncoding("iso-8859-1"))
+	open("/utf8")
+	ut.AssertEquals("windows-1252", bow.Charset())
+	ut.AssertEquals("naÃ¯ve", bow.Title())
+	ut.AssertNil(bow.SetEncoding(""))
+	open("/utf8")
+	ut.AssertEquals("naïve", bow.Title())
+	ut.AssertNotNil(bow.SetEncoding("bogus"))
+}
@@ -0,0 +1,111 @@
+package browser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/headzoo/surf/errors"
+	"github.com/headzoo/ut"
+)
+
+var htmlClick = `<!doctype html>
+<html>
+	<body>
+		<a href="/about">About   us</a>
+		<a href="/about-team">About the team</a>
+		<a href="/home"><img src="home.png" alt="Home"></a>
+		<a href="/card"><span id="card">Card</span></a>
+		<map name="nav"><area shape="rect" coords="0,0,10,10" href="/area" alt="Area"></map>
+		<form method="post" action="/submit" id="f">
+			<input type="text" name="q" value="surf">
+			<input type="image" name="pic" src="go.png" value="ignored">
+			<button name="go" value="yes">Go</button>
+			<input type="image" src="go.png" id="map">
+			<button type="button" id="plain">Plain</button>
+			<input type="submit" value="Unnamed" id="unnamed">
+			<input type="submit" name="off" value="no" disabled>
+		</form>
+		<button form="f" name="outside" value="1">Outside</button>
+		<button id="orphan">Orphan</button>
+		<p id="text">Text</p>
+	</body>
+</html>`
+
+func TestBrowserClickElements(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(htmlClick))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "<html><head><title>%s %s</title></head></html>", r.URL.Path, body)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	click := func(expr string) string {
+		err := bow.Open(ts.URL)
+		ut.AssertNil(err)
+		err = bow.Click(expr)
+		ut.AssertNil(err)
+		return bow.Title()
+	}
+	ut.AssertEquals("/about ", click("a"))
+	ut.AssertEquals("/area ", click("area"))
+	ut.AssertEquals("/card ", click("#card"))
+	ut.AssertEquals("/submit q=surf&go=yes", click("button[name=go]"))
+	ut.AssertEquals("/submit q=surf", click("#unnamed"))
+	ut.AssertEquals("/submit q=surf&outside=1", click("button[name=outside]"))
+	ut.AssertEquals("/submit q=surf&pic.x=0&pic.y=0", click("[name=pic]"))
+	ut.AssertEquals("/submit q=surf&x=0&y=0", click("#map"))
+
+	for _, expr := range []string{"#plain", "#orphan", "#text", "[name=off]", "#missing"} {
+		err := bow.Open(ts.URL)
+		ut.AssertNil(err)
+		ut.AssertNotNil(bow.Click(expr))
+	}
+}
+
+func TestBrowserClickLink(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(htmlClick))
+			return
+		}
+		fmt.Fprintf(w, "<html><head><title>%s</title></head></html>", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	clickLink := func(text string) (string, error) {
+		err := bow.Open(ts.URL)
+		ut.AssertNil(err)
+		err = bow.ClickLink(text)
+		return bow.Title(), err
+	}
+
+	title, err := clickLink("About us")
+	ut.AssertNil(err)
+	ut.AssertEquals("/about", title)
+	title, err = clickLink("the team")
+	ut.AssertNil(err)
+	ut.AssertEquals("/about-team", title)
+	title, err = clickLink("ABOUT")
+	ut.AssertNil(err)
+	ut.AssertEquals("/about", title)
+	title, err = clickLink("Home")
+	ut.AssertNil(err)
+	ut.AssertEquals("/home", title)
+	title, err = clickLink("Area")
+	ut.AssertNil(err)
+	ut.AssertEquals("/area", title)
+
+	_, err = clickLink("Missing")
+	_, ok := err.(errors.LinkNotFound)
+	ut.AssertTrue(ok)
+}
@@ -0,0 +1,125 @@
+package browser
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/headzoo/surf/errors"
+	"github.com/klauspost/compress/zstd"
+)
+
+// AcceptEncoding is the Accept-Encoding header sent with requests which don't
+// already have one. Responses are decoded whatever encodings they use, so
+// changing the header only changes what servers are asked to send.
+var AcceptEncoding = "gzip, deflate, br, zstd"
+
+// readBody reads the response body, undoing each of the content encodings
+// listed in the Content-Encoding header. Encodings are listed in the order
+// they were applied, so they are decoded from last to first.
+//
+// Responses which have no body, such as the responses to HEAD requests, are
+// not decoded, since the header describes the body a GET request would get.
+// Bodies with an encoding which isn't supported, such as the "utf-8" or
+// "none" sent by misconfigured servers, are returned undecoded.
+func readBody(resp *http.Response) ([]byte, error) {
+	if !hasBody(resp) {
+		return []byte{}, nil
+	}
+	br := bufio.NewReader(resp.Body)
+	if _, err := br.Peek(1); err == io.EOF {
+		return []byte{}, nil
+	}
+	var reader io.Reader = br
+	encodings := contentEncodings(resp.Header)
+	if unsupportedEncoding(encodings) != "" {
+		return ioutil.ReadAll(reader)
+	}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		dec, err := newDecoder(encodings[i], reader)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		reader = dec
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// hasBody returns false when the response can't have a body, which is the
+// case for the responses to HEAD requests and 1xx, 204 and 304 responses.
+func hasBody(resp *http.Response) bool {
+	if resp.Request != nil && resp.Request.Method == "HEAD" {
+		return false
+	}
+	code := resp.StatusCode
+	return !(code >= 100 && code < 200) && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+// contentEncodings returns the names of the content encodings of the
+// response, which may be listed in one header or several.
+func contentEncodings(h http.Header) []string {
+	var encodings []string
+	for _, v := range h[http.CanonicalHeaderKey("Content-Encoding")] {
+		for _, enc := range strings.Split(v, ",") {
+			enc = strings.ToLower(strings.TrimSpace(enc))
+			if enc != "" && enc != "identity" {
+				encodings = append(encodings, enc)
+			}
+		}
+	}
+	return encodings
+}
+
+// unsupportedEncoding returns the first of the encodings which can't be
+// decoded, or an empty string when every encoding is supported.
+func unsupportedEncoding(encodings []string) string {
+	for _, enc := range encodings {
+		switch enc {
+		case "gzip", "x-gzip", "deflate", "br", "zstd":
+		default:
+			return enc
+		}
+	}
+	return ""
+}
+
+// newDecoder returns a reader which decodes the named content encoding.
+func newDecoder(enc string, r io.Reader) (io.ReadCloser, error) {
+	switch enc {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// The deflate encoding is meant to be zlib wrapped, but many
+		// servers send raw deflate data instead.
+		br := bufio.NewReader(r)
+		if isZlibHeader(br) {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return ioutil.NopCloser(brotli.NewReader(r)), nil
+	case "zstd":
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return nil, errors.New("Unsupported content encoding '%s'.", enc)
+}
+
+// isZlibHeader returns true when the reader starts with a zlib header.
+func isZlibHeader(r *bufio.Reader) bool {
+	b, err := r.Peek(2)
+	if err != nil {
+		return false
+	}
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
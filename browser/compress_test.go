@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/headzoo/ut"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestContentEncoding(t *testing.T) {
+	ut.Run(t)
+	page := []byte("<html><head><title>Compressed</title></head></html>")
+	compress := func(enc string, data []byte) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch enc {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		case "zstd":
+			w, _ = zstd.NewWriter(&buf)
+		}
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+
+	var accept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept-Encoding")
+		body := page
+		encodings := strings.Split(r.URL.Query().Get("enc"), ",")
+		for _, enc := range encodings {
+			if enc != "" {
+				body = compress(enc, body)
+			}
+		}
+		header := strings.Replace(r.URL.Query().Get("enc"), "raw-", "", -1)
+		if header != "" {
+			w.Header().Set("Content-Encoding", strings.Replace(header, ",", ", ", -1))
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	for _, enc := range []string{"", "gzip", "deflate", "raw-deflate", "br", "zstd", "gzip,br", "zstd,gzip,deflate"} {
+		err := bow.Open(ts.URL + "/?enc=" + enc)
+		ut.AssertNil(err)
+		ut.AssertEquals("Compressed", bow.Title())
+	}
+	ut.AssertEquals(AcceptEncoding, accept)
+
+	bow.AddRequestHeader("Accept-Encoding", "br")
+	err := bow.Open(ts.URL + "/?enc=br")
+	ut.AssertNil(err)
+	ut.AssertEquals("br", accept)
+	ut.AssertEquals("Compressed", bow.Title())
+
+	// Unknown encodings are loaded undecoded.
+	ts2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", r.URL.Query().Get("enc"))
+		w.Write(page)
+	}))
+	defer ts2.Close()
+	for _, enc := range []string{"compress", "utf-8", "none", "gzip, compress"} {
+		err = bow.Open(ts2.URL + "/?enc=" + url.QueryEscape(enc))
+		ut.AssertNil(err)
+		ut.AssertEquals("Compressed", bow.Title())
+	}
+}
+
+func TestContentEncodingWithoutBody(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		switch r.URL.Path {
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		case "/empty":
+			w.Header().Set("Content-Length", "0")
+		default:
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte("<html><head><title>Compressed</title></head></html>"))
+			gz.Close()
+		}
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Head(ts.URL)
+	ut.AssertNil(err)
+	ut.AssertEquals(0, len(bow.state.Body))
+	for _, path := range []string{"/no-content", "/not-modified", "/empty"} {
+		err = bow.Open(ts.URL + path)
+		ut.AssertNil(err)
+		ut.AssertEquals(0, len(bow.state.Body))
+	}
+}
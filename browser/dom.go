@@ -0,0 +1,1601 @@
+package browser
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/dop251/goja"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// initDOM defines the prototypes of the objects which represent the nodes of
+// the page in scripts. The objects don't hold any state of their own, so the
+// page document is changed in place by the scripts.
+func (rt *jsRuntime) initDOM() {
+	vm := rt.vm
+	rt.nodeProto = vm.NewObject()
+	rt.elementProto = vm.CreateObject(rt.nodeProto)
+	rt.documentProto = vm.CreateObject(rt.nodeProto)
+	rt.initNode()
+	rt.initElement()
+	rt.initDocument()
+	rt.initQueries(rt.elementProto)
+	rt.initQueries(rt.documentProto)
+
+	// The constructors can't be called. They're only used by instanceof.
+	constructors := []struct {
+		name  string
+		proto *goja.Object
+	}{
+		{"Node", rt.nodeProto},
+		{"Element", rt.elementProto},
+		{"HTMLElement", rt.elementProto},
+		{"Document", rt.documentProto},
+		{"HTMLDocument", rt.documentProto},
+	}
+	for _, c := range constructors {
+		ctor, err := vm.RunString("(function () { throw new TypeError('Illegal constructor'); })")
+		if err != nil {
+			panic(err)
+		}
+		obj := ctor.ToObject(vm)
+		obj.Set("prototype", c.proto)
+		obj.Set("ELEMENT_NODE", 1)
+		obj.Set("TEXT_NODE", 3)
+		obj.Set("COMMENT_NODE", 8)
+		obj.Set("DOCUMENT_NODE", 9)
+		vm.Set(c.name, obj)
+	}
+}
+
+// wrap returns the object representing n in scripts, which is the same
+// object every time.
+func (rt *jsRuntime) wrap(n *nethtml.Node) goja.Value {
+	if n == nil {
+		return goja.Null()
+	}
+	if o, ok := rt.wrappers[n]; ok {
+		return o
+	}
+	proto := rt.nodeProto
+	switch n.Type {
+	case nethtml.ElementNode:
+		proto = rt.elementProto
+	case nethtml.DocumentNode:
+		proto = rt.documentProto
+	}
+	o := rt.vm.CreateObject(proto)
+	rt.wrappers[n] = o
+	rt.nodes[o] = n
+	if n.Type == nethtml.ElementNode && n.DataAtom == atom.Form {
+		rt.defineControls(o, n)
+	}
+	return o
+}
+
+// node returns the node represented by v, or nil when v isn't a node.
+func (rt *jsRuntime) node(v goja.Value) *nethtml.Node {
+	if o, ok := v.(*goja.Object); ok {
+		return rt.nodes[o]
+	}
+	return nil
+}
+
+// this returns the node a method was called on, and throws a TypeError when
+// it wasn't called on a node.
+func (rt *jsRuntime) this(call goja.FunctionCall) *nethtml.Node {
+	if n := rt.node(call.This); n != nil {
+		return n
+	}
+	panic(rt.vm.NewTypeError("Illegal invocation"))
+}
+
+// argNode returns the node passed as the i-th argument, and throws a
+// TypeError when the argument isn't a node.
+func (rt *jsRuntime) argNode(call goja.FunctionCall, i int) *nethtml.Node {
+	if n := rt.node(call.Argument(i)); n != nil {
+		return n
+	}
+	panic(rt.vm.NewTypeError("Argument %d is not a node.", i+1))
+}
+
+// list returns an array of the objects representing the nodes.
+func (rt *jsRuntime) list(nodes []*nethtml.Node) *goja.Object {
+	items := make([]interface{}, len(nodes))
+	for i, n := range nodes {
+		items[i] = rt.wrap(n)
+	}
+	arr := rt.vm.NewArray(items...)
+	arr.Set("item", func(call goja.FunctionCall) goja.Value {
+		i := int(call.Argument(0).ToInteger())
+		if i < 0 || i >= len(nodes) {
+			return goja.Null()
+		}
+		return rt.wrap(nodes[i])
+	})
+	return arr
+}
+
+// namedList returns an array of the objects representing the elements, which
+// are also named by their name and id attributes, such as document.forms.login.
+func (rt *jsRuntime) namedList(nodes []*nethtml.Node) *goja.Object {
+	arr := rt.list(nodes)
+	for _, attr := range []string{"id", "name"} {
+		for _, n := range nodes {
+			if name, ok := nodeAttr(n, attr); ok && name != "" && arr.Get(name) == nil {
+				arr.Set(name, rt.wrap(n))
+			}
+		}
+	}
+	return arr
+}
+
+// property defines an accessor property of a prototype, which is read-only
+// when set is nil.
+func (rt *jsRuntime) property(proto *goja.Object, name string, get func(*nethtml.Node) goja.Value, set func(*nethtml.Node, goja.Value)) {
+	getter := rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		return get(rt.this(call))
+	})
+	var setter goja.Value
+	if set != nil {
+		setter = rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			set(rt.this(call), call.Argument(0))
+			return goja.Undefined()
+		})
+	}
+	proto.DefineAccessorProperty(name, getter, setter, goja.FLAG_TRUE, goja.FLAG_TRUE)
+}
+
+// method defines a method of a prototype.
+func (rt *jsRuntime) method(proto *goja.Object, name string, fn func(*nethtml.Node, goja.FunctionCall) goja.Value) {
+	proto.Set(name, func(call goja.FunctionCall) goja.Value {
+		return fn(rt.this(call), call)
+	})
+}
+
+// initNode defines the members shared by every node.
+func (rt *jsRuntime) initNode() {
+	vm, proto := rt.vm, rt.nodeProto
+	proto.Set("ELEMENT_NODE", 1)
+	proto.Set("TEXT_NODE", 3)
+	proto.Set("COMMENT_NODE", 8)
+	proto.Set("DOCUMENT_NODE", 9)
+
+	rt.property(proto, "nodeType", func(n *nethtml.Node) goja.Value {
+		switch n.Type {
+		case nethtml.ElementNode:
+			return vm.ToValue(1)
+		case nethtml.TextNode:
+			return vm.ToValue(3)
+		case nethtml.CommentNode:
+			return vm.ToValue(8)
+		case nethtml.DocumentNode:
+			return vm.ToValue(9)
+		case nethtml.DoctypeNode:
+			return vm.ToValue(10)
+		}
+		return vm.ToValue(0)
+	}, nil)
+	rt.property(proto, "nodeName", func(n *nethtml.Node) goja.Value {
+		switch n.Type {
+		case nethtml.ElementNode:
+			return vm.ToValue(strings.ToUpper(n.Data))
+		case nethtml.TextNode:
+			return vm.ToValue("#text")
+		case nethtml.CommentNode:
+			return vm.ToValue("#comment")
+		case nethtml.DocumentNode:
+			return vm.ToValue("#document")
+		}
+		return vm.ToValue(n.Data)
+	}, nil)
+	rt.property(proto, "nodeValue", func(n *nethtml.Node) goja.Value {
+		if n.Type == nethtml.TextNode || n.Type == nethtml.CommentNode {
+			return vm.ToValue(n.Data)
+		}
+		return goja.Null()
+	}, func(n *nethtml.Node, v goja.Value) {
+		if n.Type == nethtml.TextNode || n.Type == nethtml.CommentNode {
+			n.Data = jsString(v)
+		}
+	})
+	rt.property(proto, "textContent", func(n *nethtml.Node) goja.Value {
+		switch n.Type {
+		case nethtml.DocumentNode, nethtml.DoctypeNode:
+			return goja.Null()
+		case nethtml.ElementNode:
+			return vm.ToValue(nodeText(n))
+		}
+		return vm.ToValue(n.Data)
+	}, rt.setText)
+	rt.property(proto, "parentNode", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(n.Parent)
+	}, nil)
+	rt.property(proto, "parentElement", func(n *nethtml.Node) goja.Value {
+		if n.Parent == nil || n.Parent.Type != nethtml.ElementNode {
+			return goja.Null()
+		}
+		return rt.wrap(n.Parent)
+	}, nil)
+	rt.property(proto, "childNodes", func(n *nethtml.Node) goja.Value {
+		return rt.list(childNodes(n))
+	}, nil)
+	rt.property(proto, "firstChild", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(n.FirstChild)
+	}, nil)
+	rt.property(proto, "lastChild", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(n.LastChild)
+	}, nil)
+	rt.property(proto, "nextSibling", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(n.NextSibling)
+	}, nil)
+	rt.property(proto, "previousSibling", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(n.PrevSibling)
+	}, nil)
+	rt.property(proto, "ownerDocument", func(n *nethtml.Node) goja.Value {
+		if n.Type == nethtml.DocumentNode {
+			return goja.Null()
+		}
+		return rt.wrap(rt.root())
+	}, nil)
+	rt.property(proto, "isConnected", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(rootOf(n) == rt.root())
+	}, nil)
+
+	rt.method(proto, "appendChild", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		rt.insert(n, rt.argNode(call, 0), nil)
+		return call.Argument(0)
+	})
+	rt.method(proto, "insertBefore", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		rt.insert(n, rt.argNode(call, 0), rt.node(call.Argument(1)))
+		return call.Argument(0)
+	})
+	rt.method(proto, "removeChild", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		child := rt.argNode(call, 0)
+		if child.Parent != n {
+			panic(vm.NewTypeError("The node to be removed is not a child of this node."))
+		}
+		n.RemoveChild(child)
+		return call.Argument(0)
+	})
+	rt.method(proto, "replaceChild", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		child, old := rt.argNode(call, 0), rt.argNode(call, 1)
+		if old.Parent != n {
+			panic(vm.NewTypeError("The node to be replaced is not a child of this node."))
+		}
+		if child != old {
+			rt.insert(n, child, old)
+			n.RemoveChild(old)
+		}
+		return call.Argument(1)
+	})
+	rt.method(proto, "remove", func(n *nethtml.Node, _ goja.FunctionCall) goja.Value {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+		return goja.Undefined()
+	})
+	rt.method(proto, "cloneNode", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		return rt.wrap(cloneNode(n, call.Argument(0).ToBoolean()))
+	})
+	rt.method(proto, "contains", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		for p := rt.node(call.Argument(0)); p != nil; p = p.Parent {
+			if p == n {
+				return vm.ToValue(true)
+			}
+		}
+		return vm.ToValue(false)
+	})
+	rt.method(proto, "hasChildNodes", func(n *nethtml.Node, _ goja.FunctionCall) goja.Value {
+		return vm.ToValue(n.FirstChild != nil)
+	})
+	rt.method(proto, "addEventListener", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		rt.addListener(n, call)
+		return goja.Undefined()
+	})
+	rt.method(proto, "removeEventListener", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		rt.removeListener(n, call)
+		return goja.Undefined()
+	})
+	rt.method(proto, "dispatchEvent", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		return vm.ToValue(rt.dispatch(n, call.Argument(0).ToObject(vm)))
+	})
+}
+
+// initElement defines the members of elements.
+func (rt *jsRuntime) initElement() {
+	vm, proto := rt.vm, rt.elementProto
+	rt.property(proto, "tagName", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(strings.ToUpper(n.Data))
+	}, nil)
+	rt.property(proto, "localName", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(n.Data)
+	}, nil)
+
+	// Most properties reflect an attribute.
+	reflected := map[string]string{
+		"id": "id", "className": "class", "name": "name", "title": "title",
+		"lang": "lang", "dir": "dir", "rel": "rel", "target": "target",
+		"alt": "alt", "placeholder": "placeholder", "htmlFor": "for",
+		"content": "content", "enctype": "enctype", "autocomplete": "autocomplete",
+	}
+	for name, attr := range reflected {
+		attr := attr
+		rt.property(proto, name, func(n *nethtml.Node) goja.Value {
+			v, _ := nodeAttr(n, attr)
+			return vm.ToValue(v)
+		}, func(n *nethtml.Node, v goja.Value) {
+			setAttr(n, attr, jsString(v))
+		})
+	}
+	booleans := map[string]string{
+		"disabled": "disabled", "required": "required", "readOnly": "readonly",
+		"multiple": "multiple", "hidden": "hidden", "autofocus": "autofocus",
+		"defer": "defer", "async": "async", "noValidate": "novalidate",
+		"defaultChecked": "checked", "defaultSelected": "selected",
+	}
+	for name, attr := range booleans {
+		attr := attr
+		rt.property(proto, name, func(n *nethtml.Node) goja.Value {
+			_, ok := nodeAttr(n, attr)
+			return vm.ToValue(ok)
+		}, func(n *nethtml.Node, v goja.Value) {
+			if v.ToBoolean() {
+				setAttr(n, attr, "")
+			} else {
+				removeAttr(n, attr)
+			}
+		})
+	}
+	// URLs are resolved against the page URL.
+	for _, name := range []string{"href", "src", "action"} {
+		name := name
+		rt.property(proto, name, func(n *nethtml.Node) goja.Value {
+			v, ok := nodeAttr(n, name)
+			if !ok && name == "action" {
+				return vm.ToValue(rt.url.String())
+			}
+			if !ok {
+				return vm.ToValue("")
+			}
+			u, err := rt.resolve(v)
+			if err != nil {
+				return vm.ToValue(v)
+			}
+			return vm.ToValue(u.String())
+		}, func(n *nethtml.Node, v goja.Value) {
+			setAttr(n, name, jsString(v))
+		})
+	}
+	rt.property(proto, "type", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(elementType(n))
+	}, func(n *nethtml.Node, v goja.Value) {
+		setAttr(n, "type", jsString(v))
+	})
+	rt.property(proto, "method", func(n *nethtml.Node) goja.Value {
+		m, _ := nodeAttr(n, "method")
+		if m = strings.ToLower(m); m != "post" && m != "dialog" {
+			m = "get"
+		}
+		return vm.ToValue(m)
+	}, func(n *nethtml.Node, v goja.Value) {
+		setAttr(n, "method", jsString(v))
+	})
+
+	// The state of form controls is kept in their attributes, so forms
+	// serialize the values set by scripts.
+	rt.property(proto, "value", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(controlValue(n))
+	}, func(n *nethtml.Node, v goja.Value) {
+		setControlValue(n, jsString(v))
+	})
+	rt.property(proto, "defaultValue", func(n *nethtml.Node) goja.Value {
+		if n.DataAtom == atom.Textarea {
+			return vm.ToValue(nodeText(n))
+		}
+		v, _ := nodeAttr(n, "value")
+		return vm.ToValue(v)
+	}, func(n *nethtml.Node, v goja.Value) {
+		setControlValue(n, jsString(v))
+	})
+	rt.property(proto, "checked", func(n *nethtml.Node) goja.Value {
+		_, ok := nodeAttr(n, "checked")
+		return vm.ToValue(ok)
+	}, func(n *nethtml.Node, v goja.Value) {
+		setChecked(n, v.ToBoolean())
+	})
+	rt.property(proto, "selected", func(n *nethtml.Node) goja.Value {
+		_, ok := nodeAttr(n, "selected")
+		return vm.ToValue(ok)
+	}, func(n *nethtml.Node, v goja.Value) {
+		setSelected(n, v.ToBoolean())
+	})
+	rt.property(proto, "selectedIndex", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(selectedIndex(n))
+	}, func(n *nethtml.Node, v goja.Value) {
+		for i, o := range optionElements(n) {
+			if i == int(v.ToInteger()) {
+				setAttr(o, "selected", "")
+			} else {
+				removeAttr(o, "selected")
+			}
+		}
+	})
+	rt.property(proto, "options", func(n *nethtml.Node) goja.Value {
+		return rt.list(optionElements(n))
+	}, nil)
+	rt.property(proto, "form", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(formOf(n))
+	}, nil)
+	rt.property(proto, "elements", func(n *nethtml.Node) goja.Value {
+		return rt.namedList(formElements(n))
+	}, nil)
+
+	rt.property(proto, "innerHTML", func(n *nethtml.Node) goja.Value {
+		var buf bytes.Buffer
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			nethtml.Render(&buf, c)
+		}
+		return vm.ToValue(buf.String())
+	}, func(n *nethtml.Node, v goja.Value) {
+		nodes := rt.parseFragment(jsString(v), n)
+		removeChildren(n)
+		for _, c := range nodes {
+			n.AppendChild(c)
+		}
+	})
+	rt.property(proto, "outerHTML", func(n *nethtml.Node) goja.Value {
+		var buf bytes.Buffer
+		nethtml.Render(&buf, n)
+		return vm.ToValue(buf.String())
+	}, func(n *nethtml.Node, v goja.Value) {
+		if n.Parent == nil || n.Parent.Type != nethtml.ElementNode {
+			panic(vm.NewTypeError("This element has no parent element."))
+		}
+		for _, c := range rt.parseFragment(jsString(v), n.Parent) {
+			n.Parent.InsertBefore(c, n)
+		}
+		n.Parent.RemoveChild(n)
+	})
+	rt.property(proto, "innerText", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(nodeText(n))
+	}, rt.setText)
+	rt.property(proto, "children", func(n *nethtml.Node) goja.Value {
+		return rt.list(childElements(n))
+	}, nil)
+	rt.property(proto, "childElementCount", func(n *nethtml.Node) goja.Value {
+		return vm.ToValue(len(childElements(n)))
+	}, nil)
+	rt.property(proto, "firstElementChild", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(nextElement(n.FirstChild, true))
+	}, nil)
+	rt.property(proto, "lastElementChild", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(nextElement(n.LastChild, false))
+	}, nil)
+	rt.property(proto, "nextElementSibling", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(nextElement(n.NextSibling, true))
+	}, nil)
+	rt.property(proto, "previousElementSibling", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(nextElement(n.PrevSibling, false))
+	}, nil)
+	rt.property(proto, "classList", func(n *nethtml.Node) goja.Value {
+		return rt.classList(n)
+	}, nil)
+	rt.property(proto, "style", rt.style, nil)
+
+	rt.method(proto, "getAttribute", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		if v, ok := nodeAttr(n, strings.ToLower(call.Argument(0).String())); ok {
+			return vm.ToValue(v)
+		}
+		return goja.Null()
+	})
+	rt.method(proto, "setAttribute", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		setAttr(n, strings.ToLower(call.Argument(0).String()), call.Argument(1).String())
+		return goja.Undefined()
+	})
+	rt.method(proto, "removeAttribute", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		removeAttr(n, strings.ToLower(call.Argument(0).String()))
+		return goja.Undefined()
+	})
+	rt.method(proto, "hasAttribute", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		_, ok := nodeAttr(n, strings.ToLower(call.Argument(0).String()))
+		return vm.ToValue(ok)
+	})
+	rt.method(proto, "getAttributeNames", func(n *nethtml.Node, _ goja.FunctionCall) goja.Value {
+		names := make([]interface{}, len(n.Attr))
+		for i, a := range n.Attr {
+			names[i] = a.Key
+		}
+		return vm.NewArray(names...)
+	})
+	rt.method(proto, "matches", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		return vm.ToValue(rt.selector(call.Argument(0).String()).Match(n))
+	})
+	rt.method(proto, "closest", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		sel := rt.selector(call.Argument(0).String())
+		for e := n; e != nil && e.Type == nethtml.ElementNode; e = e.Parent {
+			if sel.Match(e) {
+				return rt.wrap(e)
+			}
+		}
+		return goja.Null()
+	})
+	rt.method(proto, "insertAdjacentHTML", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		parent, ref := n, (*nethtml.Node)(nil)
+		switch strings.ToLower(call.Argument(0).String()) {
+		case "beforebegin":
+			parent, ref = n.Parent, n
+		case "afterbegin":
+			ref = n.FirstChild
+		case "beforeend":
+		case "afterend":
+			parent, ref = n.Parent, n.NextSibling
+		default:
+			panic(vm.NewTypeError("Invalid position '%s'.", call.Argument(0).String()))
+		}
+		if parent == nil || parent.Type != nethtml.ElementNode {
+			panic(vm.NewTypeError("This element has no parent element."))
+		}
+		for _, c := range rt.parseFragment(call.Argument(1).String(), parent) {
+			parent.InsertBefore(c, ref)
+		}
+		return goja.Undefined()
+	})
+	rt.method(proto, "append", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		for _, c := range rt.argNodes(call) {
+			rt.insert(n, c, nil)
+		}
+		return goja.Undefined()
+	})
+	rt.method(proto, "prepend", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		ref := n.FirstChild
+		for _, c := range rt.argNodes(call) {
+			rt.insert(n, c, ref)
+		}
+		return goja.Undefined()
+	})
+	rt.method(proto, "click", func(n *nethtml.Node, _ goja.FunctionCall) goja.Value {
+		rt.click(n)
+		return goja.Undefined()
+	})
+	rt.method(proto, "submit", func(n *nethtml.Node, _ goja.FunctionCall) goja.Value {
+		if n.DataAtom == atom.Form {
+			rt.submit(n, nil, false)
+		}
+		return goja.Undefined()
+	})
+	rt.method(proto, "requestSubmit", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		if n.DataAtom == atom.Form {
+			rt.submit(n, rt.node(call.Argument(0)), true)
+		}
+		return goja.Undefined()
+	})
+	for _, name := range []string{"reset", "focus", "blur", "select", "scrollIntoView"} {
+		rt.method(proto, name, func(*nethtml.Node, goja.FunctionCall) goja.Value {
+			return goja.Undefined()
+		})
+	}
+	rt.method(proto, "getBoundingClientRect", func(*nethtml.Node, goja.FunctionCall) goja.Value {
+		rect := vm.NewObject()
+		for _, name := range []string{"x", "y", "top", "right", "bottom", "left", "width", "height"} {
+			rect.Set(name, 0)
+		}
+		return rect
+	})
+}
+
+// initDocument defines the members of the document.
+func (rt *jsRuntime) initDocument() {
+	vm, proto := rt.vm, rt.documentProto
+	rt.property(proto, "documentElement", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(nextElement(n.FirstChild, true))
+	}, nil)
+	rt.property(proto, "head", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(rt.child(atom.Head))
+	}, nil)
+	rt.property(proto, "body", func(n *nethtml.Node) goja.Value {
+		return rt.wrap(rt.child(atom.Body))
+	}, nil)
+	rt.property(proto, "title", func(n *nethtml.Node) goja.Value {
+		if title := findElement(n, atom.Title); title != nil {
+			return vm.ToValue(collapseSpace(nodeText(title)))
+		}
+		return vm.ToValue("")
+	}, func(n *nethtml.Node, v goja.Value) {
+		title := findElement(n, atom.Title)
+		if title == nil {
+			head := rt.child(atom.Head)
+			if head == nil {
+				return
+			}
+			title = &nethtml.Node{Type: nethtml.ElementNode, Data: "title", DataAtom: atom.Title}
+			head.AppendChild(title)
+		}
+		rt.setText(title, v)
+	})
+	rt.property(proto, "cookie", func(*nethtml.Node) goja.Value {
+		return vm.ToValue(rt.cookie())
+	}, func(_ *nethtml.Node, v goja.Value) {
+		rt.setCookie(jsString(v))
+	})
+	rt.property(proto, "readyState", func(*nethtml.Node) goja.Value {
+		if rt.loading {
+			return vm.ToValue("loading")
+		}
+		return vm.ToValue("complete")
+	}, nil)
+	for _, name := range []string{"URL", "documentURI"} {
+		rt.property(proto, name, func(*nethtml.Node) goja.Value {
+			return vm.ToValue(rt.url.String())
+		}, nil)
+	}
+	rt.property(proto, "referrer", func(*nethtml.Node) goja.Value {
+		if req := rt.bow.state.Request; req != nil {
+			return vm.ToValue(req.Referer())
+		}
+		return vm.ToValue("")
+	}, nil)
+	rt.property(proto, "domain", func(*nethtml.Node) goja.Value {
+		return vm.ToValue(rt.url.Hostname())
+	}, nil)
+	rt.property(proto, "characterSet", func(*nethtml.Node) goja.Value {
+		if cs := rt.bow.Charset(); cs != "" {
+			return vm.ToValue(strings.ToUpper(cs))
+		}
+		return vm.ToValue("UTF-8")
+	}, nil)
+	rt.property(proto, "location", func(*nethtml.Node) goja.Value {
+		return rt.location
+	}, func(_ *nethtml.Node, v goja.Value) {
+		rt.assign(v.String())
+	})
+	rt.property(proto, "currentScript", func(*nethtml.Node) goja.Value {
+		return rt.wrap(rt.current)
+	}, nil)
+	rt.property(proto, "defaultView", func(*nethtml.Node) goja.Value {
+		return vm.GlobalObject()
+	}, nil)
+	collections := map[string]func(*nethtml.Node) bool{
+		"forms":   func(e *nethtml.Node) bool { return e.DataAtom == atom.Form },
+		"images":  func(e *nethtml.Node) bool { return e.DataAtom == atom.Img },
+		"scripts": func(e *nethtml.Node) bool { return e.DataAtom == atom.Script },
+		"links": func(e *nethtml.Node) bool {
+			_, ok := nodeAttr(e, "href")
+			return ok && (e.DataAtom == atom.A || e.DataAtom == atom.Area)
+		},
+	}
+	for name, match := range collections {
+		match := match
+		rt.property(proto, name, func(n *nethtml.Node) goja.Value {
+			return rt.namedList(findElements(n, match))
+		}, nil)
+	}
+
+	rt.method(proto, "getElementById", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		id := call.Argument(0).String()
+		found := findElements(n, func(e *nethtml.Node) bool {
+			v, ok := nodeAttr(e, "id")
+			return ok && v == id
+		})
+		if len(found) == 0 {
+			return goja.Null()
+		}
+		return rt.wrap(found[0])
+	})
+	rt.method(proto, "getElementsByName", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		name := call.Argument(0).String()
+		return rt.list(findElements(n, func(e *nethtml.Node) bool {
+			v, ok := nodeAttr(e, "name")
+			return ok && v == name
+		}))
+	})
+	rt.method(proto, "createElement", func(_ *nethtml.Node, call goja.FunctionCall) goja.Value {
+		tag := strings.ToLower(call.Argument(0).String())
+		return rt.wrap(&nethtml.Node{Type: nethtml.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))})
+	})
+	rt.method(proto, "createTextNode", func(_ *nethtml.Node, call goja.FunctionCall) goja.Value {
+		return rt.wrap(&nethtml.Node{Type: nethtml.TextNode, Data: call.Argument(0).String()})
+	})
+	rt.method(proto, "createComment", func(_ *nethtml.Node, call goja.FunctionCall) goja.Value {
+		return rt.wrap(&nethtml.Node{Type: nethtml.CommentNode, Data: call.Argument(0).String()})
+	})
+	rt.method(proto, "createEvent", func(*nethtml.Node, goja.FunctionCall) goja.Value {
+		evt := rt.newEvent("", false)
+		evt.Set("initEvent", func(call goja.FunctionCall) goja.Value {
+			evt.Set("type", call.Argument(0).String())
+			evt.Set("bubbles", call.Argument(1).ToBoolean())
+			evt.Set("cancelable", call.Argument(2).ToBoolean())
+			return goja.Undefined()
+		})
+		return evt
+	})
+	rt.method(proto, "write", func(_ *nethtml.Node, call goja.FunctionCall) goja.Value {
+		rt.write(joinArguments(call))
+		return goja.Undefined()
+	})
+	rt.method(proto, "writeln", func(_ *nethtml.Node, call goja.FunctionCall) goja.Value {
+		rt.write(joinArguments(call) + "\n")
+		return goja.Undefined()
+	})
+	for _, name := range []string{"open", "close"} {
+		rt.method(proto, name, func(*nethtml.Node, goja.FunctionCall) goja.Value {
+			return goja.Undefined()
+		})
+	}
+}
+
+// initQueries defines the methods which find the descendants of elements and
+// the document.
+func (rt *jsRuntime) initQueries(proto *goja.Object) {
+	rt.method(proto, "querySelector", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		return rt.wrap(cascadia.Query(n, rt.selector(call.Argument(0).String())))
+	})
+	rt.method(proto, "querySelectorAll", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		return rt.list(cascadia.QueryAll(n, rt.selector(call.Argument(0).String())))
+	})
+	rt.method(proto, "getElementsByTagName", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		tag := strings.ToLower(call.Argument(0).String())
+		return rt.list(findElements(n, func(e *nethtml.Node) bool {
+			return tag == "*" || e.Data == tag
+		}))
+	})
+	rt.method(proto, "getElementsByClassName", func(n *nethtml.Node, call goja.FunctionCall) goja.Value {
+		want := strings.Fields(call.Argument(0).String())
+		return rt.list(findElements(n, func(e *nethtml.Node) bool {
+			v, _ := nodeAttr(e, "class")
+			classes := strings.Fields(v)
+			for _, w := range want {
+				if !containsString(classes, w) {
+					return false
+				}
+			}
+			return len(want) > 0
+		}))
+	})
+}
+
+// selector compiles a CSS selector, and throws an error when it's invalid.
+func (rt *jsRuntime) selector(s string) cascadia.SelectorGroup {
+	sel, err := cascadia.ParseGroup(s)
+	if err != nil {
+		panic(rt.vm.NewGoError(err))
+	}
+	return sel
+}
+
+// child returns the head or body element of the page.
+func (rt *jsRuntime) child(a atom.Atom) *nethtml.Node {
+	html := firstChildElement(rt.root(), atom.Html)
+	if html == nil {
+		return nil
+	}
+	return firstChildElement(html, a)
+}
+
+// insert inserts child into parent before ref, or last when ref is nil,
+// moving it from wherever it was. Scripts inserted into the document run.
+func (rt *jsRuntime) insert(parent, child, ref *nethtml.Node) {
+	for p := parent; p != nil; p = p.Parent {
+		if p == child {
+			panic(rt.vm.NewTypeError("The new child contains the parent."))
+		}
+	}
+	if ref != nil && ref.Parent != parent {
+		panic(rt.vm.NewTypeError("The node before which the new node is to be inserted is not a child of this node."))
+	}
+	if child == ref {
+		return
+	}
+	if child.Parent != nil {
+		child.Parent.RemoveChild(child)
+	}
+	parent.InsertBefore(child, ref)
+	rt.connected(child)
+}
+
+// connected runs the scripts in a subtree which was inserted into the
+// document. Scripts without a src attribute or any code yet are left until
+// they're inserted again.
+func (rt *jsRuntime) connected(n *nethtml.Node) {
+	if rootOf(n) != rt.root() {
+		return
+	}
+	var scripts []*nethtml.Node
+	walkElements(n, func(e *nethtml.Node) {
+		if e.DataAtom != atom.Script || rt.executed[e] {
+			return
+		}
+		if _, ok := nodeAttr(e, "src"); ok || nodeText(e) != "" {
+			scripts = append(scripts, e)
+		}
+	})
+	for _, s := range scripts {
+		rt.runElement(s)
+	}
+}
+
+// write implements document.write, which inserts the markup after the
+// running script, or at the end of the body when no script is running.
+func (rt *jsRuntime) write(markup string) {
+	var parent, ref *nethtml.Node
+	if rt.cursor != nil && rt.cursor.Parent != nil {
+		parent, ref = rt.cursor.Parent, rt.cursor.NextSibling
+	} else if parent = rt.child(atom.Body); parent == nil {
+		return
+	}
+	// Markup written in the head is parsed as body content, which keeps the
+	// elements the parser would otherwise move out of the head.
+	context := parent
+	if context.DataAtom == atom.Head {
+		context = &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	}
+	nodes := rt.parseFragment(markup, context)
+	for _, c := range nodes {
+		parent.InsertBefore(c, ref)
+		if rt.cursor != nil {
+			rt.cursor = c
+		}
+	}
+	for _, c := range nodes {
+		rt.connected(c)
+	}
+}
+
+// parseFragment parses markup in the context of an element.
+func (rt *jsRuntime) parseFragment(markup string, context *nethtml.Node) []*nethtml.Node {
+	if context.Type != nethtml.ElementNode {
+		context = &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	}
+	nodes, err := nethtml.ParseFragment(strings.NewReader(markup), context)
+	if err != nil {
+		panic(rt.vm.NewGoError(err))
+	}
+	return nodes
+}
+
+// setText replaces the children of an element with the text, or sets the
+// text of a text or comment node.
+func (rt *jsRuntime) setText(n *nethtml.Node, v goja.Value) {
+	switch n.Type {
+	case nethtml.TextNode, nethtml.CommentNode:
+		n.Data = jsString(v)
+	case nethtml.ElementNode:
+		removeChildren(n)
+		if s := jsString(v); s != "" {
+			n.AppendChild(&nethtml.Node{Type: nethtml.TextNode, Data: s})
+		}
+	}
+}
+
+// argNodes returns the arguments of append and prepend as nodes, which
+// makes text nodes of strings.
+func (rt *jsRuntime) argNodes(call goja.FunctionCall) []*nethtml.Node {
+	var nodes []*nethtml.Node
+	for _, arg := range call.Arguments {
+		n := rt.node(arg)
+		if n == nil {
+			n = &nethtml.Node{Type: nethtml.TextNode, Data: arg.String()}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// defineControls defines the named properties of a form, such as
+// form.username, for the controls in the form when the form is first used
+// by the scripts. Names already used by the members of forms are skipped.
+func (rt *jsRuntime) defineControls(o *goja.Object, form *nethtml.Node) {
+	for _, c := range formElements(form) {
+		for _, attr := range []string{"name", "id"} {
+			name, ok := nodeAttr(c, attr)
+			if !ok || name == "" || o.Get(name) != nil {
+				continue
+			}
+			rt.define(o, name, func() goja.Value {
+				named := findElements(rootOf(form), func(e *nethtml.Node) bool {
+					if formOf(e) != form {
+						return false
+					}
+					n, _ := nodeAttr(e, "name")
+					id, _ := nodeAttr(e, "id")
+					return n == name || id == name
+				})
+				switch len(named) {
+				case 0:
+					return goja.Undefined()
+				case 1:
+					return rt.wrap(named[0])
+				}
+				return rt.list(named)
+			}, nil)
+		}
+	}
+}
+
+// classList returns the classList object of an element.
+func (rt *jsRuntime) classList(n *nethtml.Node) goja.Value {
+	vm := rt.vm
+	classes := func() []string {
+		v, _ := nodeAttr(n, "class")
+		return strings.Fields(v)
+	}
+	without := func(c []string, name string) []string {
+		var kept []string
+		for _, s := range c {
+			if s != name {
+				kept = append(kept, s)
+			}
+		}
+		return kept
+	}
+	list := vm.NewObject()
+	rt.define(list, "length", func() goja.Value {
+		return vm.ToValue(len(classes()))
+	}, nil)
+	list.Set("contains", func(call goja.FunctionCall) goja.Value {
+		return vm.ToValue(containsString(classes(), call.Argument(0).String()))
+	})
+	list.Set("item", func(call goja.FunctionCall) goja.Value {
+		c, i := classes(), int(call.Argument(0).ToInteger())
+		if i < 0 || i >= len(c) {
+			return goja.Null()
+		}
+		return vm.ToValue(c[i])
+	})
+	list.Set("add", func(call goja.FunctionCall) goja.Value {
+		c := classes()
+		for _, arg := range call.Arguments {
+			if !containsString(c, arg.String()) {
+				c = append(c, arg.String())
+			}
+		}
+		setAttr(n, "class", strings.Join(c, " "))
+		return goja.Undefined()
+	})
+	list.Set("remove", func(call goja.FunctionCall) goja.Value {
+		c := classes()
+		for _, arg := range call.Arguments {
+			c = without(c, arg.String())
+		}
+		setAttr(n, "class", strings.Join(c, " "))
+		return goja.Undefined()
+	})
+	list.Set("toggle", func(call goja.FunctionCall) goja.Value {
+		c, name := classes(), call.Argument(0).String()
+		on := !containsString(c, name)
+		if force := call.Argument(1); !goja.IsUndefined(force) {
+			on = force.ToBoolean()
+		}
+		c = without(c, name)
+		if on {
+			c = append(c, name)
+		}
+		setAttr(n, "class", strings.Join(c, " "))
+		return vm.ToValue(on)
+	})
+	list.Set("toString", func(goja.FunctionCall) goja.Value {
+		return vm.ToValue(strings.Join(classes(), " "))
+	})
+	return list
+}
+
+// style returns the style object of an element. Styles aren't computed, so
+// the object only holds the properties set by the style attribute and the
+// scripts.
+func (rt *jsRuntime) style(n *nethtml.Node) goja.Value {
+	if s, ok := rt.styles[n]; ok {
+		return s
+	}
+	s := rt.vm.NewObject()
+	if v, ok := nodeAttr(n, "style"); ok {
+		for _, decl := range strings.Split(v, ";") {
+			if parts := strings.SplitN(decl, ":", 2); len(parts) == 2 {
+				s.Set(cssProperty(parts[0]), strings.TrimSpace(parts[1]))
+			}
+		}
+	}
+	s.Set("getPropertyValue", func(call goja.FunctionCall) goja.Value {
+		v := s.Get(cssProperty(call.Argument(0).String()))
+		if v == nil || goja.IsUndefined(v) {
+			return rt.vm.ToValue("")
+		}
+		return v
+	})
+	s.Set("setProperty", func(call goja.FunctionCall) goja.Value {
+		s.Set(cssProperty(call.Argument(0).String()), call.Argument(1).String())
+		return goja.Undefined()
+	})
+	s.Set("removeProperty", func(call goja.FunctionCall) goja.Value {
+		s.Delete(cssProperty(call.Argument(0).String()))
+		return goja.Undefined()
+	})
+	rt.styles[n] = s
+	return s
+}
+
+// cssProperty converts a CSS property name to the name used by style
+// objects, eg "background-color" to "backgroundColor".
+func cssProperty(name string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(name)), "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// addListener implements addEventListener. Only functions are supported as
+// listeners, and a listener is added to the same node once.
+func (rt *jsRuntime) addListener(n *nethtml.Node, call goja.FunctionCall) {
+	typ, fn := call.Argument(0).String(), call.Argument(1)
+	if _, ok := goja.AssertFunction(fn); !ok {
+		return
+	}
+	if rt.listeners[n] == nil {
+		rt.listeners[n] = make(map[string][]goja.Value)
+	}
+	for _, l := range rt.listeners[n][typ] {
+		if l.SameAs(fn) {
+			return
+		}
+	}
+	rt.listeners[n][typ] = append(rt.listeners[n][typ], fn)
+}
+
+// removeListener implements removeEventListener.
+func (rt *jsRuntime) removeListener(n *nethtml.Node, call goja.FunctionCall) {
+	typ, fn := call.Argument(0).String(), call.Argument(1)
+	var kept []goja.Value
+	for _, l := range rt.listeners[n][typ] {
+		if !l.SameAs(fn) {
+			kept = append(kept, l)
+		}
+	}
+	if rt.listeners[n] != nil {
+		rt.listeners[n][typ] = kept
+	}
+}
+
+// newEvent returns a cancelable event.
+func (rt *jsRuntime) newEvent(typ string, bubbles bool) *goja.Object {
+	evt := rt.vm.NewObject()
+	evt.Set("type", typ)
+	evt.Set("bubbles", bubbles)
+	evt.Set("cancelable", true)
+	evt.Set("defaultPrevented", false)
+	return evt
+}
+
+// eventConstructor implements the Event and CustomEvent constructors.
+func (rt *jsRuntime) eventConstructor(call goja.ConstructorCall) *goja.Object {
+	evt := call.This
+	evt.Set("type", call.Argument(0).String())
+	evt.Set("bubbles", false)
+	evt.Set("cancelable", false)
+	evt.Set("detail", goja.Null())
+	if init, ok := call.Argument(1).(*goja.Object); ok {
+		for _, name := range []string{"bubbles", "cancelable", "detail"} {
+			if v := init.Get(name); v != nil && !goja.IsUndefined(v) {
+				evt.Set(name, v)
+			}
+		}
+	}
+	evt.Set("defaultPrevented", false)
+	return nil
+}
+
+// dispatch fires the event at target, which is the window when nil, and at
+// the ancestors of the target when the event bubbles. It returns false when
+// the event was cancelled.
+func (rt *jsRuntime) dispatch(target *nethtml.Node, evt *goja.Object) bool {
+	typ := jsString(evt.Get("type"))
+	var stopped, prevented bool
+	evt.Set("preventDefault", func(goja.FunctionCall) goja.Value {
+		prevented = true
+		evt.Set("defaultPrevented", true)
+		return goja.Undefined()
+	})
+	stop := func(goja.FunctionCall) goja.Value {
+		stopped = true
+		return goja.Undefined()
+	}
+	evt.Set("stopPropagation", stop)
+	evt.Set("stopImmediatePropagation", stop)
+	evt.Set("target", rt.target(target))
+	evt.Set("srcElement", rt.target(target))
+	evt.Set("timeStamp", rt.now)
+
+	path := []*nethtml.Node{target}
+	if bubbles := evt.Get("bubbles"); target != nil && bubbles != nil && bubbles.ToBoolean() {
+		for p := target.Parent; p != nil; p = p.Parent {
+			path = append(path, p)
+		}
+		if rootOf(target) == rt.root() {
+			path = append(path, nil)
+		}
+	}
+	for _, n := range path {
+		if stopped {
+			break
+		}
+		this := rt.target(n)
+		evt.Set("currentTarget", this)
+		if rt.handler(n, typ, this, evt) {
+			prevented = true
+			evt.Set("defaultPrevented", true)
+		}
+		for _, l := range append([]goja.Value(nil), rt.listeners[n][typ]...) {
+			rt.handled++
+			rt.call(l, this, evt)
+		}
+	}
+	evt.Set("currentTarget", goja.Null())
+	return !prevented
+}
+
+// target returns the object an event is fired at.
+func (rt *jsRuntime) target(n *nethtml.Node) goja.Value {
+	if n == nil {
+		return rt.vm.GlobalObject()
+	}
+	return rt.wrap(n)
+}
+
+// windowEvents are the events of the window which are handled by the event
+// handler attributes of the body element, such as <body onload="...">.
+var windowEvents = map[string]bool{
+	"load": true, "unload": true, "beforeunload": true, "hashchange": true, "pageshow": true,
+}
+
+// handler calls the event handler property of n, such as onclick, or its
+// event handler attribute when the property isn't set. It returns true when
+// the handler returned false, which cancels the event.
+func (rt *jsRuntime) handler(n *nethtml.Node, typ string, this goja.Value, evt *goja.Object) bool {
+	var fn goja.Value
+	attrs := n
+	if n == nil {
+		fn = rt.vm.GlobalObject().Get("on" + typ)
+		if windowEvents[typ] {
+			attrs = rt.child(atom.Body)
+		}
+	} else if o, ok := rt.wrappers[n]; ok {
+		fn = o.Get("on" + typ)
+	}
+	if _, ok := goja.AssertFunction(fn); !ok {
+		fn = nil
+		if attrs != nil && attrs.Type == nethtml.ElementNode {
+			if code, ok := nodeAttr(attrs, "on"+typ); ok {
+				fn = rt.compile(code)
+			}
+		}
+	}
+	if fn == nil {
+		return false
+	}
+	rt.handled++
+	return rt.call(fn, this, evt).StrictEquals(rt.vm.ToValue(false))
+}
+
+// compile compiles the code of an event handler attribute into a function.
+func (rt *jsRuntime) compile(code string) goja.Value {
+	fn, err := rt.vm.RunString("(function (event) {\n" + code + "\n})")
+	if err != nil {
+		rt.fail(err)
+		return nil
+	}
+	return fn
+}
+
+// click fires the click event at n, then runs the default action of the
+// element unless the event was cancelled.
+func (rt *jsRuntime) click(n *nethtml.Node) {
+	if isFormControl(n) && isControlDisabled(n) {
+		return
+	}
+	// Checkboxes and radio buttons change before the listeners run, and
+	// change back when the event is cancelled.
+	var undo func()
+	if n.DataAtom == atom.Input {
+		switch elementType(n) {
+		case "checkbox":
+			_, was := nodeAttr(n, "checked")
+			setChecked(n, !was)
+			undo = func() { setChecked(n, was) }
+		case "radio":
+			var was *nethtml.Node
+			for _, r := range radioGroup(n) {
+				if _, ok := nodeAttr(r, "checked"); ok {
+					was = r
+				}
+			}
+			setChecked(n, true)
+			undo = func() {
+				if was != nil {
+					setChecked(was, true)
+				} else {
+					setChecked(n, false)
+				}
+			}
+		}
+	}
+	if undo != nil {
+		rt.handled++
+	}
+	if !rt.dispatch(n, rt.newEvent("click", true)) {
+		if undo != nil {
+			undo()
+		}
+		return
+	}
+	rt.activate(n)
+}
+
+// activate runs the default action of a click on n, which follows the link
+// n is in, or submits the form of the submit button n is in.
+func (rt *jsRuntime) activate(n *nethtml.Node) {
+	for e := n; e != nil && e.Type == nethtml.ElementNode; e = e.Parent {
+		switch e.DataAtom {
+		case atom.A, atom.Area:
+			if href, ok := nodeAttr(e, "href"); ok {
+				rt.assign(href)
+				return
+			}
+		case atom.Button, atom.Input:
+			if t := elementType(e); t == "submit" || t == "image" {
+				if form := formOf(e); form != nil && !isControlDisabled(e) {
+					rt.submit(form, e, true)
+				}
+			}
+			return
+		}
+	}
+}
+
+// submit submits the form once the scripts have stopped. The submit event is
+// fired first when fire is true, which is when the form is submitted by a
+// button or requestSubmit rather than by form.submit().
+func (rt *jsRuntime) submit(form, submitter *nethtml.Node, fire bool) {
+	if fire && !rt.dispatch(form, rt.newEvent("submit", true)) {
+		return
+	}
+	rt.navigate(func() error {
+		f := NewForm(rt.bow, selectionOf(form))
+		if submitter != nil {
+			return f.submitWith(selectionOf(submitter))
+		}
+		return f.send("")
+	})
+}
+
+// clickElement clicks n for Browser.Click. It returns false when nothing in
+// the page handled the click, in which case the browser clicks the element
+// the same way it does without scripts.
+func (rt *jsRuntime) clickElement(n *nethtml.Node) (bool, error) {
+	if isFormControl(n) && isControlDisabled(n) {
+		return false, nil
+	}
+	handled := rt.handled
+	stop := rt.limit()
+	rt.click(n)
+	rt.runTimers()
+	stop()
+	if rt.navigation != nil {
+		return true, rt.follow()
+	}
+	return rt.handled != handled, nil
+}
+
+// formOf returns the form which owns the control, or nil.
+func formOf(n *nethtml.Node) *nethtml.Node {
+	ids := make(map[string]*nethtml.Node)
+	indexIds(rootOf(n), ids)
+	return formOwner(n, ids)
+}
+
+// formElements returns the controls owned by the form, including the
+// disabled controls which aren't submitted.
+func formElements(form *nethtml.Node) []*nethtml.Node {
+	if form.DataAtom != atom.Form {
+		return nil
+	}
+	ids := make(map[string]*nethtml.Node)
+	root := rootOf(form)
+	indexIds(root, ids)
+	return findElements(root, func(e *nethtml.Node) bool {
+		switch e.DataAtom {
+		case atom.Input, atom.Button, atom.Select, atom.Textarea, atom.Fieldset, atom.Output, atom.Object:
+			return formOwner(e, ids) == form
+		}
+		return false
+	})
+}
+
+// radioGroup returns the radio buttons in the same group as n, which have the
+// same name and form.
+func radioGroup(n *nethtml.Node) []*nethtml.Node {
+	name, ok := nodeAttr(n, "name")
+	if !ok || name == "" {
+		return []*nethtml.Node{n}
+	}
+	owner := formOf(n)
+	return findElements(rootOf(n), func(e *nethtml.Node) bool {
+		v, _ := nodeAttr(e, "name")
+		return e.DataAtom == atom.Input && v == name && elementType(e) == "radio" && formOf(e) == owner
+	})
+}
+
+// setChecked checks or unchecks a checkbox or radio button. Checking a radio
+// button unchecks the others in its group.
+func setChecked(n *nethtml.Node, checked bool) {
+	if !checked {
+		removeAttr(n, "checked")
+		return
+	}
+	setAttr(n, "checked", "")
+	if n.DataAtom == atom.Input && elementType(n) == "radio" {
+		for _, r := range radioGroup(n) {
+			if r != n {
+				removeAttr(r, "checked")
+			}
+		}
+	}
+}
+
+// setSelected selects or deselects an option. Selecting an option of a
+// select element which isn't multiple deselects the other options.
+func setSelected(n *nethtml.Node, selected bool) {
+	if !selected {
+		removeAttr(n, "selected")
+		return
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Select {
+			if _, multiple := nodeAttr(p, "multiple"); !multiple {
+				for _, o := range optionElements(p) {
+					removeAttr(o, "selected")
+				}
+			}
+			break
+		}
+	}
+	setAttr(n, "selected", "")
+}
+
+// optionElements returns the options of a select element.
+func optionElements(n *nethtml.Node) []*nethtml.Node {
+	if n.DataAtom != atom.Select {
+		return nil
+	}
+	return findElements(n, func(e *nethtml.Node) bool {
+		return e.DataAtom == atom.Option
+	})
+}
+
+// selectedIndex returns the index of the first selected option of a select
+// element. The first option is selected when none are, unless the select
+// element is multiple.
+func selectedIndex(n *nethtml.Node) int {
+	options := optionElements(n)
+	for i, o := range options {
+		if _, ok := nodeAttr(o, "selected"); ok {
+			return i
+		}
+	}
+	if _, multiple := nodeAttr(n, "multiple"); !multiple && len(options) > 0 {
+		return 0
+	}
+	return -1
+}
+
+// optionValue returns the value of an option, which is its text when it
+// doesn't have a value attribute.
+func optionValue(n *nethtml.Node) string {
+	if v, ok := nodeAttr(n, "value"); ok {
+		return v
+	}
+	return collapseSpace(nodeText(n))
+}
+
+// controlValue returns the value property of an element.
+func controlValue(n *nethtml.Node) string {
+	switch n.DataAtom {
+	case atom.Textarea:
+		return nodeText(n)
+	case atom.Select:
+		if i := selectedIndex(n); i >= 0 {
+			return optionValue(optionElements(n)[i])
+		}
+		return ""
+	case atom.Option:
+		return optionValue(n)
+	}
+	v, ok := nodeAttr(n, "value")
+	if t := elementType(n); !ok && n.DataAtom == atom.Input && (t == "checkbox" || t == "radio") {
+		return "on"
+	}
+	return v
+}
+
+// setControlValue sets the value property of an element. Setting the value
+// of a select element selects the first option with the value.
+func setControlValue(n *nethtml.Node, v string) {
+	switch n.DataAtom {
+	case atom.Textarea:
+		removeChildren(n)
+		n.AppendChild(&nethtml.Node{Type: nethtml.TextNode, Data: v})
+	case atom.Select:
+		found := false
+		for _, o := range optionElements(n) {
+			if !found && optionValue(o) == v {
+				setAttr(o, "selected", "")
+				found = true
+			} else {
+				removeAttr(o, "selected")
+			}
+		}
+	default:
+		setAttr(n, "value", v)
+	}
+}
+
+// elementType returns the type property of an element, which takes the
+// default types of input and button elements into account.
+func elementType(n *nethtml.Node) string {
+	t, _ := nodeAttr(n, "type")
+	switch n.DataAtom {
+	case atom.Input:
+		if t = strings.ToLower(t); t == "" {
+			return "text"
+		}
+	case atom.Button:
+		return controlType(selectionOf(n))
+	}
+	return t
+}
+
+// isFormControl returns true for the elements which can be disabled.
+func isFormControl(n *nethtml.Node) bool {
+	switch n.DataAtom {
+	case atom.Input, atom.Button, atom.Select, atom.Textarea:
+		return true
+	}
+	return false
+}
+
+// selectionOf returns a selection holding only n.
+func selectionOf(n *nethtml.Node) *goquery.Selection {
+	return goquery.NewDocumentFromNode(n).Selection
+}
+
+// walkElements calls fn for n, when it's an element, and for each element
+// below n in document order.
+func walkElements(n *nethtml.Node, fn func(*nethtml.Node)) {
+	if n.Type == nethtml.ElementNode {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkElements(c, fn)
+	}
+}
+
+// findElements returns the elements below n which match.
+func findElements(n *nethtml.Node, match func(*nethtml.Node) bool) []*nethtml.Node {
+	var found []*nethtml.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkElements(c, func(e *nethtml.Node) {
+			if match(e) {
+				found = append(found, e)
+			}
+		})
+	}
+	return found
+}
+
+// findElement returns the first element below n with the given tag, or nil.
+func findElement(n *nethtml.Node, a atom.Atom) *nethtml.Node {
+	found := findElements(n, func(e *nethtml.Node) bool {
+		return e.DataAtom == a
+	})
+	if len(found) == 0 {
+		return nil
+	}
+	return found[0]
+}
+
+// childElements returns the children of n which are elements.
+func childElements(n *nethtml.Node) []*nethtml.Node {
+	var elements []*nethtml.Node
+	for c := nextElement(n.FirstChild, true); c != nil; c = nextElement(c.NextSibling, true) {
+		elements = append(elements, c)
+	}
+	return elements
+}
+
+// nextElement returns the first element from n onwards through its following
+// siblings, or through its preceding siblings when forward is false.
+func nextElement(n *nethtml.Node, forward bool) *nethtml.Node {
+	for n != nil && n.Type != nethtml.ElementNode {
+		if forward {
+			n = n.NextSibling
+		} else {
+			n = n.PrevSibling
+		}
+	}
+	return n
+}
+
+// rootOf returns the node at the top of the tree holding n.
+func rootOf(n *nethtml.Node) *nethtml.Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+// cloneNode returns a copy of n, which includes its descendants when deep is true.
+func cloneNode(n *nethtml.Node, deep bool) *nethtml.Node {
+	c := &nethtml.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]nethtml.Attribute(nil), n.Attr...),
+	}
+	if deep {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			c.AppendChild(cloneNode(child, true))
+		}
+	}
+	return c
+}
+
+// removeChildren removes the children of n.
+func removeChildren(n *nethtml.Node) {
+	for n.FirstChild != nil {
+		n.RemoveChild(n.FirstChild)
+	}
+}
+
+// setAttr sets the named attribute of n.
+func setAttr(n *nethtml.Node, name, value string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, nethtml.Attribute{Key: name, Val: value})
+}
+
+// removeAttr removes the named attribute of n.
+func removeAttr(n *nethtml.Node, name string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
+
+// jsString converts a value to a string, treating null and undefined as the
+// empty string the way the DOM does for most string properties.
+func jsString(v goja.Value) string {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return ""
+	}
+	return v.String()
+}
+
+// joinArguments joins the arguments of a call as strings.
+func joinArguments(call goja.FunctionCall) string {
+	var parts []string
+	for _, arg := range call.Arguments {
+		parts = append(parts, arg.String())
+	}
+	return strings.Join(parts, "")
+}
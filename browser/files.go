@@ -0,0 +1,282 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/headzoo/surf/errors"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// File represents a input type file, that includes the fileName and a io.reader
+type File struct {
+	fileName    string
+	data        io.Reader
+	path        string
+	contentType string
+	size        int64
+}
+
+// NewFile creates and returns a *File which uploads the data with the given
+// file name. The content type is detected from the file name extension or
+// from the data unless it's set with SetContentType.
+func NewFile(fileName string, data io.Reader) *File {
+	return &File{fileName: fileName, data: data, size: readerSize(data)}
+}
+
+// OpenFile creates and returns a *File which uploads the file at the given
+// path. The file is opened when the form is submitted, and the base name of the
+// path is used as the file name.
+func OpenFile(path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.New("Cannot open the file '%s': %s", path, err).
+			With(errors.Fields{Field: path, Err: err})
+	}
+	if info.IsDir() {
+		return nil, errors.New("Cannot upload '%s', it is a directory.", path).
+			With(errors.Fields{Field: path})
+	}
+	return &File{fileName: filepath.Base(path), path: path, size: -1}, nil
+}
+
+// Name returns the file name sent with the file.
+func (f *File) Name() string {
+	return f.fileName
+}
+
+// Size returns the number of bytes in the file, or -1 when the size is not
+// known before the file is read.
+func (f *File) Size() int64 {
+	if f.path != "" {
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return -1
+		}
+		return info.Size()
+	}
+	return f.size
+}
+
+// SetContentType overrides the detected content type of the file.
+func (f *File) SetContentType(ct string) *File {
+	f.contentType = ct
+	return f
+}
+
+// ContentType returns the content type sent with the file.
+//
+// The type set with SetContentType is used when available. Otherwise the type
+// is found from the file name extension, and finally by sniffing the first
+// bytes of the data. Files without data are sent as application/octet-stream.
+func (f *File) ContentType() string {
+	if f.contentType != "" {
+		return f.contentType
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(f.fileName)); ct != "" {
+		f.contentType = mediaType(ct)
+		return f.contentType
+	}
+	head, err := f.peek()
+	if err != nil || len(head) == 0 {
+		return "application/octet-stream"
+	}
+	f.contentType = mediaType(http.DetectContentType(head))
+	return f.contentType
+}
+
+// peek returns the first bytes of the file data without consuming them.
+func (f *File) peek() ([]byte, error) {
+	if f.path != "" {
+		fh, err := os.Open(f.path)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		head := make([]byte, sniffLen)
+		n, err := io.ReadFull(fh, head)
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			err = nil
+		}
+		return head[:n], err
+	}
+	if f.data == nil {
+		return nil, nil
+	}
+	pos := int64(-1)
+	if s, ok := f.data.(io.Seeker); ok {
+		if cur, err := s.Seek(0, io.SeekCurrent); err == nil {
+			pos = cur
+		}
+	}
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f.data, head)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	head = head[:n]
+	if pos >= 0 {
+		// Seekable data is moved back rather than wrapped, so it can
+		// still be read again when the body is sent again.
+		if _, serr := f.data.(io.Seeker).Seek(pos, io.SeekStart); serr == nil {
+			return head, err
+		}
+	}
+	f.data = io.MultiReader(bytes.NewReader(head), f.data)
+	return head, err
+}
+
+// rewinder returns the function which moves the data of the file back to
+// where it is now, and false when the data can't be read again. Files opened
+// from a path are opened again instead, so nothing needs to be moved.
+func (f *File) rewinder() (func() error, bool) {
+	if f.path != "" || f.data == nil {
+		return nil, true
+	}
+	s, ok := f.data.(io.Seeker)
+	if !ok {
+		return nil, false
+	}
+	pos, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false
+	}
+	return func() error {
+		_, err := s.Seek(pos, io.SeekStart)
+		return err
+	}, true
+}
+
+// open returns a reader for the file data. The returned closer must be called
+// once the data has been read.
+func (f *File) open() (io.Reader, func() error, error) {
+	if f.path != "" {
+		fh, err := os.Open(f.path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return fh, fh.Close, nil
+	}
+	if f.data == nil {
+		return bytes.NewReader(nil), func() error { return nil }, nil
+	}
+	return f.data, func() error { return nil }, nil
+}
+
+// accepts returns a boolean indicating whether the file matches one of the
+// tokens of an accept attribute. An empty accept attribute matches any file.
+func (f *File) accepts(accept string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(f.fileName))
+	ct := strings.ToLower(f.ContentType())
+	for _, token := range strings.Split(accept, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		switch {
+		case token == "":
+			continue
+		case strings.HasPrefix(token, "."):
+			if token == ext {
+				return true
+			}
+		case strings.HasSuffix(token, "/*"):
+			if strings.HasPrefix(ct, strings.TrimSuffix(token, "*")) {
+				return true
+			}
+		case token == ct:
+			return true
+		}
+	}
+	return false
+}
+
+// FileSet represents a map of files used to port multipart
+type FileSet map[string]*File
+
+// MultiFileSet represents a map of files used to post multipart, which may
+// hold several files for each name, the same as an input type file with the
+// multiple attribute.
+type MultiFileSet map[string][]*File
+
+// Add appends the file to the files with the given name.
+func (fs MultiFileSet) Add(name string, file *File) {
+	fs[name] = append(fs[name], file)
+}
+
+// Set replaces the files with the given name with the given files.
+func (fs MultiFileSet) Set(name string, files ...*File) {
+	fs[name] = files
+}
+
+// fileInput describes an input type file element.
+type fileInput struct {
+	multiple bool
+	accept   string
+}
+
+// writeFileHeader writes the headers of a file part to the multipart body. A
+// nil file writes the headers a browser sends for a file input without files.
+func writeFileHeader(w *multipart.Writer, name string, file *File) error {
+	fileName, ct := "", "application/octet-stream"
+	if file != nil {
+		fileName, ct = file.fileName, file.ContentType()
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(name), escapeQuotes(fileName)))
+	h.Set("Content-Type", ct)
+	_, err := w.CreatePart(h)
+	return err
+}
+
+// readerSize returns the number of unread bytes in the reader, or -1 when the
+// size can't be found without reading it.
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case nil:
+		return 0
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err = v.Seek(cur, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - cur
+	}
+	return -1
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes escapes the quotes in a Content-Disposition parameter value.
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// mediaType removes any parameters from a content type.
+func mediaType(ct string) string {
+	if i := strings.Index(ct, ";"); i != -1 {
+		ct = ct[:i]
+	}
+	return strings.TrimSpace(ct)
+}
@@ -0,0 +1,355 @@
+package browser
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/surf/errors"
+)
+
+var (
+	fileType      = reflect.TypeOf((*File)(nil))
+	marshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// Fill sets the form fields from the fields of the struct v, which may be a
+// struct or a pointer to a struct.
+//
+// Struct fields are mapped onto form controls using the form tag, which holds
+// the control name, eg `form:"email"`. Fields without a form tag, or with the
+// tag `form:"-"`, are skipped, and the omitempty option skips fields holding
+// their zero value, eg `form:"phone,omitempty"`.
+//
+// Tagged fields holding a struct set the controls of the nested struct using
+// the tag as a prefix, so the field City tagged `form:"city"` inside a struct
+// tagged `form:"address"` inside a struct tagged `form:"user"` sets the
+// control named "user[address][city]". Untagged embedded structs are filled
+// without a prefix.
+//
+// Bools check or uncheck a checkbox, slices select every value of a select
+// multiple or check every matching checkbox in a group, and *File or []*File
+// values set the files of an input type file. Any other value is converted to
+// a string which sets the value of an input or textarea, picks the option of a
+// select by its value or label, or checks the radio button with that value.
+//
+// Every tagged field is filled when possible. The names of the tagged fields
+// which don't match a form control are returned in an ElementNotFound error.
+//
+// Fill isn't part of the Submittable interface, so the forms returned by
+// Browser.Form are filled through a type assertion, eg form.(*Form).Fill(v).
+func (f *Form) Fill(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return errors.NewInvalidFormValue("Cannot fill the form from a nil value.")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.NewInvalidFormValue(
+			"Cannot fill the form from a value of type %s.", rv.Type())
+	}
+
+	fill := &formFiller{
+		form:     f,
+		controls: indexFormControls(f.selection),
+	}
+	if err := fill.fillStruct(rv, ""); err != nil {
+		return err
+	}
+	if len(fill.missing) > 0 {
+		// The Field is the first missing name, and the message lists them all.
+		return errors.NewElementNotFound(
+			"No form controls found with the names '%s'.", strings.Join(fill.missing, "', '")).
+			With(errors.Fields{Field: fill.missing[0]})
+	}
+	return nil
+}
+
+// formControl describes the controls in a form which share a name.
+type formControl struct {
+	// kind is "select", "textarea", or the type of an input or button.
+	kind string
+
+	// values holds the value attribute of every radio or checkbox.
+	values []string
+}
+
+// indexFormControls maps the name of every control in the form to a description
+// of the control.
+func indexFormControls(sel *goquery.Selection) map[string]*formControl {
+	controls := make(map[string]*formControl)
+	formControls(sel).Each(func(_ int, s *goquery.Selection) {
+		name, ok := s.Attr("name")
+		if !ok {
+			return
+		}
+		kind := controlType(s)
+		if s.Is("select") {
+			kind = "select"
+		} else if s.Is("textarea") {
+			kind = "textarea"
+		}
+		c, ok := controls[name]
+		if !ok {
+			c = &formControl{kind: kind}
+			controls[name] = c
+		}
+		if kind == "checkbox" || kind == "radio" {
+			val, _ := s.Attr("value")
+			c.values = append(c.values, val)
+		}
+	})
+	return controls
+}
+
+// formFiller sets form fields from the fields of a struct.
+type formFiller struct {
+	form     *Form
+	controls map[string]*formControl
+	missing  []string
+}
+
+// fillStruct fills the form from the fields of the struct v. Control names are
+// nested inside of the prefix when the prefix is not empty.
+func (ff *formFiller) fillStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		tag, ok := sf.Tag.Lookup("form")
+		if !ok {
+			if sf.Anonymous && indirectType(sf.Type).Kind() == reflect.Struct {
+				if fv = indirect(fv); fv.IsValid() {
+					if err := ff.fillStruct(fv, prefix); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if !fv.CanInterface() {
+			continue
+		}
+
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i != -1 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if prefix != "" {
+			name = prefix + "[" + name + "]"
+		}
+		if hasTagOption(opts, "omitempty") && isZero(fv) {
+			continue
+		}
+		if err := ff.fillField(fv, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillField sets the control with the given name from the value v.
+func (ff *formFiller) fillField(v reflect.Value, name string) error {
+	if v.Type() == fileType || (v.Kind() == reflect.Slice && v.Type().Elem() == fileType) {
+		return ff.fillFiles(v, name)
+	}
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Struct && !isTextValue(v) {
+		return ff.fillStruct(v, name)
+	}
+
+	c, ok := ff.controls[name]
+	if !ok {
+		ff.missing = append(ff.missing, name)
+		return nil
+	}
+	if v.Kind() == reflect.Bool && c.kind == "checkbox" {
+		if v.Bool() {
+			return ff.form.Check(name)
+		}
+		return ff.form.UnCheck(name)
+	}
+
+	var vals []string
+	if (v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8) || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			s, err := formString(v.Index(i), name)
+			if err != nil {
+				return err
+			}
+			vals = append(vals, s)
+		}
+	} else {
+		s, err := formString(v, name)
+		if err != nil {
+			return err
+		}
+		vals = []string{s}
+	}
+	return ff.setValues(c, name, vals)
+}
+
+// setValues sets the control with the given name to the values.
+func (ff *formFiller) setValues(c *formControl, name string, vals []string) error {
+	f := ff.form
+	switch c.kind {
+	case "select":
+		if len(vals) == 0 {
+			f.fields.Del(name)
+			return nil
+		}
+		if err := f.SelectByOptionValue(name, vals...); err == nil {
+			return nil
+		}
+		return f.SelectByOptionLabel(name, vals...)
+	case "radio", "checkbox":
+		if c.kind == "radio" && len(vals) > 1 {
+			return invalidControlValue(name,
+				"The radio buttons with name '%s' cannot hold multiple values.", name)
+		}
+		for _, val := range vals {
+			if !containsString(c.values, val) {
+				return invalidControlValue(name,
+					"No %s found with name '%s' and value '%s'.", c.kind, name, val)
+			}
+		}
+		f.fields.Del(name)
+		for _, val := range vals {
+			f.fields = f.order.insert(f.fields, name, val)
+		}
+		return nil
+	case "submit", "reset", "button", "image", "file":
+		return invalidControlValue(name,
+			"The %s with name '%s' cannot be filled with a value.", c.kind, name)
+	}
+
+	f.fields.Del(name)
+	for _, val := range vals {
+		f.fields = f.order.insert(f.fields, name, val)
+	}
+	return nil
+}
+
+// fillFiles sets the files of the input type file with the given name from a
+// *File or []*File value.
+func (ff *formFiller) fillFiles(v reflect.Value, name string) error {
+	if _, ok := ff.form.inputs[name]; !ok {
+		ff.missing = append(ff.missing, name)
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ff.form.SetFiles(name)
+		}
+		return ff.form.SetFiles(name, v.Interface().(*File))
+	}
+	return ff.form.SetFiles(name, v.Interface().([]*File)...)
+}
+
+// formString converts the value v to the string submitted with a form.
+func formString(v reflect.Value, name string) (string, error) {
+	if v.CanAddr() && !v.Type().Implements(marshalerType) && reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(marshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	if v.Type().Implements(stringerType) {
+		return v.Interface().(fmt.Stringer).String(), nil
+	}
+	v = indirect(v)
+	if !v.IsValid() {
+		return "", nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+	return "", invalidControlValue(name,
+		"Cannot convert a value of type %s for the form control '%s'.", v.Type(), name)
+}
+
+// isTextValue returns true when the value converts itself to text.
+func isTextValue(v reflect.Value) bool {
+	return v.Type().Implements(marshalerType) || v.Type().Implements(stringerType) ||
+		reflect.PtrTo(v.Type()).Implements(marshalerType)
+}
+
+// indirect follows pointers until reaching a non-pointer value. An invalid
+// value is returned when a nil pointer is found.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// indirectType returns the type pointed at by t when t is a pointer type.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isZero returns true when the value is the zero value of its type.
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// hasTagOption returns true when the comma separated options contain the option.
+func hasTagOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if strings.TrimSpace(o) == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString returns true when the slice contains the string.
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
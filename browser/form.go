@@ -3,12 +3,16 @@ package browser
 import (
 	"html"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"io"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/headzoo/surf/errors"
+	"golang.org/x/net/html/atom"
+
+	nethtml "golang.org/x/net/html"
 )
 
 // Submittable represents an element that may be submitted, such as a form.
@@ -77,17 +81,23 @@ type Form struct {
 	selection *goquery.Selection
 	method    string
 	action    string
-	fields    url.Values
+	fields    FormValues
 	buttons   url.Values
 	checkboxs url.Values
 	selects   selects
-	files     FileSet
+	files     MultiFileSet
+	inputs    map[string]fileInput
+	order     fieldOrder
 }
 
 // NewForm creates and returns a *Form type.
 func NewForm(bow Browsable, s *goquery.Selection) *Form {
-	fields, buttons, checkboxs, selects, files := serializeForm(s)
+	fields, buttons, checkboxs, selects, inputs, order := serializeForm(s)
 	method, action := formAttributes(bow, s)
+	files := make(MultiFileSet, len(inputs))
+	for name := range inputs {
+		files[name] = nil
+	}
 
 	return &Form{
 		bow:       bow,
@@ -99,6 +109,8 @@ func NewForm(bow Browsable, s *goquery.Selection) *Form {
 		checkboxs: checkboxs,
 		selects:   selects,
 		files:     files,
+		inputs:    inputs,
+		order:     order,
 	}
 }
 
@@ -116,36 +128,75 @@ func (f *Form) Action() string {
 // Input sets the value of a form field.
 // it returns an ElementNotFound error if the field does not exist
 func (f *Form) Input(name, value string) error {
-	if _, ok := f.fields[name]; ok {
+	if f.fields.Has(name) {
 		f.fields.Set(name, value)
 		return nil
 	}
-	return errors.NewElementNotFound("No input found with name '%s'.", name)
+	return controlNotFound(name, "No input found with name '%s'.", name)
 }
 
 // File sets the value for an form input type file,
 // it returns an ElementNotFound error if the field does not exists
 func (f *Form) File(name string, fileName string, data io.Reader) error {
-
-	if _, ok := f.files[name]; ok {
-		f.files[name] = &File{fileName: fileName, data: data}
-		return nil
-	}
-	return errors.NewElementNotFound(
-		"No input type 'file' found with name '%s'.", name)
+	return f.SetFiles(name, NewFile(fileName, data))
 }
 
 // SetFile sets the value for a form input type file.
 // It will add the field to the form if necessary
 func (f *Form) SetFile(name string, fileName string, data io.Reader) {
-	f.files[name] = &File{fileName: fileName, data: data}
+	f.files.Set(name, NewFile(fileName, data))
+}
+
+// AddFile adds a file to a form input type file.
+// It returns an ElementNotFound error if the field does not exist, and an
+// InvalidFormValue error when the input already holds a file and does not have
+// the multiple attribute, or when the file does not match the input accept attribute.
+func (f *Form) AddFile(name string, file *File) error {
+	input, ok := f.inputs[name]
+	if !ok {
+		return controlNotFound(name,
+			"No input type 'file' found with name '%s'.", name)
+	}
+	if !input.multiple && len(f.files[name]) > 0 {
+		return invalidControlValue(name,
+			"The input type 'file' with name '%s' does not accept multiple files.", name)
+	}
+	if !file.accepts(input.accept) {
+		return invalidControlValue(name,
+			"The input type 'file' with name '%s' does not accept the file '%s'.", name, file.Name())
+	}
+	f.files.Add(name, file)
+	return nil
+}
+
+// SetFiles replaces the files of a form input type file with the given files.
+// It returns the same errors as AddFile, and leaves the current files in place
+// when an error is returned.
+func (f *Form) SetFiles(name string, files ...*File) error {
+	input, ok := f.inputs[name]
+	if !ok {
+		return controlNotFound(name,
+			"No input type 'file' found with name '%s'.", name)
+	}
+	if !input.multiple && len(files) > 1 {
+		return invalidControlValue(name,
+			"The input type 'file' with name '%s' does not accept multiple files.", name)
+	}
+	for _, file := range files {
+		if !file.accepts(input.accept) {
+			return invalidControlValue(name,
+				"The input type 'file' with name '%s' does not accept the file '%s'.", name, file.Name())
+		}
+	}
+	f.files.Set(name, files...)
+	return nil
 }
 
 // Set will set the value of a form field if it exists,
 // or create and set it if it does not.
 func (f *Form) Set(name, value string) error {
-	if _, ok := f.fields[name]; !ok {
-		f.fields.Add(name, value)
+	if !f.fields.Has(name) {
+		f.fields = f.order.insert(f.fields, name, value)
 		return nil
 	}
 	return f.Input(name, value)
@@ -153,16 +204,20 @@ func (f *Form) Set(name, value string) error {
 
 // Get will return the value of a form field and `ok` - whether the field exists or not
 func (f *Form) Get(name string) []string {
-	return f.fields[name]
+	return f.fields.GetAll(name)
 }
 
 // Check sets the checkbox value to its active state.
 func (f *Form) Check(name string) error {
 	if _, ok := f.checkboxs[name]; ok {
-		f.fields.Set(name, f.checkboxs.Get(name))
+		if f.fields.Has(name) {
+			f.fields.Set(name, f.checkboxs.Get(name))
+		} else {
+			f.fields = f.order.insert(f.fields, name, f.checkboxs.Get(name))
+		}
 		return nil
 	}
-	return errors.NewElementNotFound("No checkbox found with name '%s'.", name)
+	return controlNotFound(name, "No checkbox found with name '%s'.", name)
 }
 
 // UnCheck sets the checkbox value to inactive state.
@@ -171,16 +226,15 @@ func (f *Form) UnCheck(name string) error {
 		f.fields.Del(name)
 		return nil
 	}
-	return errors.NewElementNotFound("No checkbox found with name '%s'.", name)
+	return controlNotFound(name, "No checkbox found with name '%s'.", name)
 }
 
 // IsChecked returns the current state of the checkbox
 func (f *Form) IsChecked(name string) (bool, error) {
 	if _, ok := f.checkboxs[name]; ok {
-		_, found := f.fields[name]
-		return found, nil
+		return f.fields.Has(name), nil
 	}
-	return false, errors.NewElementNotFound("No checkbox found with name '%s'.", name)
+	return false, controlNotFound(name, "No checkbox found with name '%s'.", name)
 }
 
 // Remove will remove the form field if it exists.
@@ -192,29 +246,19 @@ func (f *Form) Remove(name string) {
 // found, error is returned.  For multiple value form element such as select multiple,
 // the first value is returned.
 func (f *Form) Value(name string) (string, error) {
-	if _, ok := f.fields[name]; ok {
+	if f.fields.Has(name) {
 		return f.fields.Get(name), nil
 	}
-	return "", errors.NewElementNotFound("No input found with name '%s'.", name)
+	return "", controlNotFound(name, "No input found with name '%s'.", name)
 }
 
 // RemoveValue will remove a single instance of a form value whose name and value match.
 // This is valuable for removing a single value from a select multiple.
 func (f *Form) RemoveValue(name, val string) error {
-	if _, ok := f.fields[name]; !ok {
-		return errors.NewElementNotFound("No input found with name '%s'.", name)
-	}
-	var save []string
-	for _, v := range f.fields[name] {
-		if v != val {
-			save = append(save, v)
-		}
-	}
-	if len(save) == 0 {
-		f.fields.Del(name)
-	} else {
-		f.fields[name] = save
+	if !f.fields.Has(name) {
+		return controlNotFound(name, "No input found with name '%s'.", name)
 	}
+	f.fields.DelValue(name, val)
 	return nil
 }
 
@@ -223,17 +267,17 @@ func (f *Form) RemoveValue(name, val string) error {
 func (f *Form) SelectByOptionLabel(name string, optionLabel ...string) error {
 	s, ok := f.selects[name]
 	if !ok {
-		return errors.NewElementNotFound("No select element found with name '%s'.", name)
+		return controlNotFound(name, "No select element found with name '%s'.", name)
 	}
 	if len(optionLabel) > 1 && !s.multiple {
-		return errors.NewElementNotFound("The select element with name '%s' is not a select miltiple.", name)
+		return controlNotFound(name, "The select element with name '%s' is not a select miltiple.", name)
 	}
 	f.fields.Del(name)
 	for _, l := range optionLabel {
 		if _, ok := s.labels[l]; !ok {
-			return errors.NewElementNotFound("The select element with name %q does not have an option with label %q", name, l)
+			return controlNotFound(name, "The select element with name %q does not have an option with label %q", name, l)
 		}
-		f.fields.Add(name, s.labels.Get(l))
+		f.fields = f.order.insert(f.fields, name, s.labels.Get(l))
 	}
 	return nil
 }
@@ -243,17 +287,17 @@ func (f *Form) SelectByOptionLabel(name string, optionLabel ...string) error {
 func (f *Form) SelectByOptionValue(name string, optionValue ...string) error {
 	s, ok := f.selects[name]
 	if !ok {
-		return errors.NewElementNotFound("No select element found with name '%s'.", name)
+		return controlNotFound(name, "No select element found with name '%s'.", name)
 	}
 	if len(optionValue) > 1 && !s.multiple {
-		return errors.NewElementNotFound("The select element with name '%s' is not a select miltiple.", name)
+		return controlNotFound(name, "The select element with name '%s' is not a select miltiple.", name)
 	}
 	f.fields.Del(name)
 	for _, v := range optionValue {
 		if _, ok := s.values[v]; !ok {
-			return errors.NewElementNotFound("The select element with name %q does not have an option with value %q", name, v)
+			return controlNotFound(name, "The select element with name %q does not have an option with value %q", name, v)
 		}
-		f.fields.Add(name, v)
+		f.fields = f.order.insert(f.fields, name, v)
 	}
 	return nil
 }
@@ -261,10 +305,10 @@ func (f *Form) SelectByOptionValue(name string, optionValue ...string) error {
 // SelectValues returns the current values of a form element whose name matches.  If name is not
 // found, error is returned.  For select multiple elements, all values are returned.
 func (f *Form) SelectValues(name string) ([]string, error) {
-	if _, ok := f.fields[name]; ok {
-		return f.fields[name], nil
+	if f.fields.Has(name) {
+		return f.fields.GetAll(name), nil
 	}
-	return nil, errors.NewElementNotFound("No input found with name '%s'.", name)
+	return nil, controlNotFound(name, "No input found with name '%s'.", name)
 }
 
 // SelectLabels returns the labels for the selected options for a select form element whose name
@@ -272,10 +316,10 @@ func (f *Form) SelectValues(name string) ([]string, error) {
 func (f *Form) SelectLabels(name string) ([]string, error) {
 	s, ok := f.selects[name]
 	if !ok {
-		return nil, errors.NewElementNotFound("No select element found with name '%s'.", name)
+		return nil, controlNotFound(name, "No select element found with name '%s'.", name)
 	}
 	var labels []string
-	for _, v := range f.fields[name] {
+	for _, v := range f.fields.GetAll(name) {
 		labels = append(labels, s.values.Get(v))
 	}
 	return labels, nil
@@ -285,27 +329,33 @@ func (f *Form) SelectLabels(name string) ([]string, error) {
 // Clicks the first button in the form, or submits the form without using
 // any button when the form does not contain any buttons.
 func (f *Form) Submit() error {
-	if len(f.buttons) > 0 {
-		for name := range f.buttons {
-			return f.Click(name)
+	first := ""
+	for name := range f.buttons {
+		if first == "" || f.order[name] < f.order[first] {
+			first = name
 		}
 	}
-	return f.send("", "")
+	defer f.lockNavigation()()
+	if first != "" {
+		return f.send(first, FormValue{Name: first, Value: f.buttons[first][0]})
+	}
+	return f.send("")
 }
 
 // Click submits the form by clicking the button with the given name.
 func (f *Form) Click(button string) error {
 	if _, ok := f.buttons[button]; !ok {
-		return errors.NewInvalidFormValue(
+		return invalidControlValue(button,
 			"Form does not contain a button with the name '%s'.", button)
 	}
-	return f.send(button, f.buttons[button][0])
+	defer f.lockNavigation()()
+	return f.send(button, FormValue{Name: button, Value: f.buttons[button][0]})
 }
 
 // Click submits the form by clicking the button with the given name and value.
 func (f *Form) ClickByValue(name, value string) error {
 	if _, ok := f.buttons[name]; !ok {
-		return errors.NewInvalidFormValue(
+		return invalidControlValue(name,
 			"Form does not contain a button with the name '%s'.", name)
 	}
 	valueNotFound := true
@@ -316,10 +366,32 @@ func (f *Form) ClickByValue(name, value string) error {
 		}
 	}
 	if valueNotFound {
-		return errors.NewInvalidFormValue(
+		return invalidControlValue(name,
 			"Form does not contain a button with the name '%s' and value '%s'.", name, value)
 	}
-	return f.send(name, value)
+	defer f.lockNavigation()()
+	return f.send(name, FormValue{Name: name, Value: value})
+}
+
+// submitWith submits the form as if the given submit button was clicked. The
+// name and value of the button are sent when the button has a name. Image
+// buttons send the coordinates of the click instead, which are always 0,0,
+// as the fields name.x and name.y, or x and y when the button has no name.
+func (f *Form) submitWith(button *goquery.Selection) error {
+	name := button.AttrOr("name", "")
+	switch controlType(button) {
+	case "image":
+		prefix := ""
+		if name != "" {
+			prefix = name + "."
+		}
+		return f.send(name, FormValue{Name: prefix + "x", Value: "0"}, FormValue{Name: prefix + "y", Value: "0"})
+	case "submit":
+		if name != "" {
+			return f.send(name, FormValue{Name: name, Value: button.AttrOr("value", "")})
+		}
+	}
+	return f.send("")
 }
 
 // Dom returns the inner *goquery.Selection.
@@ -327,8 +399,10 @@ func (f *Form) Dom() *goquery.Selection {
 	return f.selection
 }
 
-// send submits the form.
-func (f *Form) send(buttonName, buttonValue string) error {
+// send submits the form. The submitter values are sent at the position of the
+// control with the given name, which is the button used to submit the form.
+// Forms of a *Browser are sent once its navigation lock is held.
+func (f *Form) send(control string, submitter ...FormValue) (err error) {
 	method, ok := f.selection.Attr("method")
 	if !ok {
 		method = "GET"
@@ -343,95 +417,467 @@ func (f *Form) send(buttonName, buttonValue string) error {
 	}
 	aurl = f.bow.ResolveUrl(aurl)
 
-	values := make(url.Values, len(f.fields)+1)
-	for name, vals := range f.fields {
-		values[name] = vals
+	// Forms of a *Browser are traced, logged and sent in document order, and
+	// forms of other browsables are sent through the Browsable interface.
+	bow, _ := f.bow.(*Browser)
+	if bow != nil {
+		end := bow.traceOperation("surf.Submit", map[string]interface{}{
+			"url.full":            aurl.String(),
+			"http.request.method": strings.ToUpper(method),
+		})
+		defer func() { end(err) }()
+	}
+
+	values := f.fields.Copy()
+	if len(submitter) > 0 {
+		for _, v := range submitter {
+			values.Del(v.Name)
+		}
+		values = f.order.insertAt(values, control, submitter...)
 	}
-	if buttonName != "" {
-		values.Set(buttonName, buttonValue)
+	values, err = encodeFormValues(values, formCharset(f.selection, pageCharset(f.bow.State())))
+	if err != nil {
+		return err
 	}
 
+	enctype, _ := f.selection.Attr("enctype")
+	if bow != nil {
+		names := make([]string, 0, len(values))
+		for _, v := range values {
+			names = append(names, v.Name)
+		}
+		bow.Logger().Info("form submit", "method", strings.ToUpper(method), "action", aurl.String(),
+			"enctype", enctype, "fields", names)
+	}
+
+	if bow == nil {
+		return f.sendValues(method, aurl.String(), enctype, values)
+	}
 	if strings.ToUpper(method) == "GET" {
-		return f.bow.OpenForm(aurl.String(), values)
+		aurl.RawQuery = values.Encode()
+		return bow.open(aurl.String())
 	}
-	enctype, _ := f.selection.Attr("enctype")
-	if enctype == "multipart/form-data" {
-		return f.bow.PostMultipart(aurl.String(), values, f.files)
+	switch strings.ToLower(enctype) {
+	case "multipart/form-data":
+		return bow.postMultipart(aurl.String(), f.order.multipartParts(values, f.files))
+	case "text/plain":
+		return bow.post(aurl.String(), "text/plain", strings.NewReader(encodeTextPlain(values)))
+	}
+	return bow.post(aurl.String(), "application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
+
+// lockNavigation holds the navigation lock of the browser of the form until
+// the returned function is called. Nothing is locked for browsables other
+// than *Browser.
+func (f *Form) lockNavigation() func() {
+	if bow, ok := f.bow.(*Browser); ok {
+		return bow.lockNavigation()
+	}
+	return func() {}
+}
+
+// sendValues submits the form through the methods of the Browsable interface,
+// which is how forms of browsables other than *Browser are submitted. Those
+// methods take url.Values, so the fields aren't sent in document order, and
+// only the first file of each file input is sent.
+func (f *Form) sendValues(method, action, enctype string, values FormValues) error {
+	if strings.ToUpper(method) == "GET" {
+		return f.bow.OpenForm(action, values.UrlValues())
+	}
+	switch strings.ToLower(enctype) {
+	case "multipart/form-data":
+		files := make(FileSet, len(f.files))
+		for name, fs := range f.files {
+			if len(fs) > 0 {
+				files[name] = fs[0]
+			}
+		}
+		return f.bow.PostMultipart(action, values.UrlValues(), files)
+	case "text/plain":
+		return f.bow.Post(action, "text/plain", strings.NewReader(encodeTextPlain(values)))
 	}
-	return f.bow.PostForm(aurl.String(), values)
+	return f.bow.PostForm(action, values.UrlValues())
 }
 
-// serializeForm converts the form fields into a url.Values type.
-// Returns two url.Value types. The first is the form field values, and the
-// second is the form button values.
-func serializeForm(sel *goquery.Selection) (url.Values, url.Values, url.Values, selects, FileSet) {
-	fields := make(url.Values)
+// serializeForm converts the form fields into a FormValues type.
+// Returns the form field values in document order, the form button values,
+// the checkbox values, the select options, the file inputs, and the document
+// position of every named control.
+//
+// The fields are gathered from every control whose form owner is the given
+// form, which includes controls placed outside of the form element that
+// reference it through the form attribute. Disabled controls, including the
+// ones inside a disabled fieldset, are skipped.
+func serializeForm(sel *goquery.Selection) (FormValues, url.Values, url.Values, selects, map[string]fileInput, fieldOrder) {
+	var fields FormValues
 	buttons := make(url.Values)
 	checkboxs := make(url.Values)
 	selects := make(selects)
-	files := make(FileSet)
-	sel.Find("input,button,textarea").Each(func(_ int, s *goquery.Selection) {
-		if v, ok := s.Attr("disabled"); ok && strings.ToLower(v) == "disabled" {
+	files := make(map[string]fileInput)
+	order := make(fieldOrder)
+	formControls(sel).Each(func(_ int, s *goquery.Selection) {
+		name, ok := s.Attr("name")
+		if !ok {
 			return
 		}
-		if name, ok := s.Attr("name"); ok {
-			val, _ := s.Attr("value")
-			t, _ := s.Attr("type")
-			t = strings.ToLower(t)
-			if t == "submit" {
-				buttons.Add(name, val)
-			} else if t == "checkbox" || t == "radio" {
-				if c, found := s.Attr("checked"); found && strings.ToLower(c) == "checked" {
-					fields.Add(name, val)
-				}
-				if t == "checkbox" {
-					checkboxs.Add(name, val)
-				}
-			} else if t == "file" {
-				files[name] = &File{}
-			} else {
+		if _, ok := order[name]; !ok {
+			order[name] = len(order)
+		}
+		if s.Is("select") {
+			serializeSelect(s, name, &fields, selects)
+			return
+		}
+
+		val, _ := s.Attr("value")
+		if s.Is("textarea") {
+			val = s.Text()
+		}
+		t := controlType(s)
+		if t == "submit" {
+			buttons.Add(name, val)
+		} else if t == "reset" || t == "button" || t == "image" {
+			// Image buttons are only sent when they submit the form.
+			return
+		} else if t == "checkbox" || t == "radio" {
+			if _, found := s.Attr("checked"); found {
 				fields.Add(name, val)
 			}
+			if t == "checkbox" {
+				checkboxs.Add(name, val)
+			}
+		} else if t == "file" {
+			_, multiple := s.Attr("multiple")
+			accept, _ := s.Attr("accept")
+			files[name] = fileInput{multiple: multiple, accept: accept}
+		} else {
+			fields.Add(name, val)
+			if dirname, ok := s.Attr("dirname"); ok && dirname != "" && hasDirname(s, t) {
+				if _, ok := order[dirname]; !ok {
+					order[dirname] = len(order)
+				}
+				fields.Add(dirname, directionality(s, val))
+			}
 		}
 	})
 
-	sel.Find("select").Each(func(_ int, s *goquery.Selection) {
-		if v, ok := s.Attr("disabled"); ok && strings.ToLower(v) == "disabled" {
+	return fields, buttons, checkboxs, selects, files, order
+}
+
+// serializeSelect adds the options of a select element to the selects, and
+// adds the selected values to the fields.
+//
+// Like in web browsers, the first option which isn't disabled is selected
+// when no option is selected in a select which is neither a select multiple
+// nor displayed as a list box by a size above 1.
+func serializeSelect(s *goquery.Selection, name string, fields *FormValues, selects selects) {
+	_, multiple := s.Attr("multiple")
+	selects[name] = selectOptions{
+		multiple: multiple,
+		values:   make(url.Values),
+		labels:   make(url.Values),
+	}
+	var foundSelected bool
+	first, hasFirst := "", false
+	s.Find(`option`).Each(func(_ int, ss *goquery.Selection) {
+		val, _ := ss.Attr("value")
+		l, _ := ss.Html()
+		selects[name].values.Add(val, strings.TrimSpace(html.UnescapeString(l)))
+		selects[name].labels.Add(strings.TrimSpace(html.UnescapeString(l)), val)
+		if foundSelected || isOptionDisabled(ss) {
 			return
 		}
-		if name, ok := s.Attr("name"); ok {
-			_, multiple := s.Attr("multiple")
-			selects[name] = selectOptions{
-				multiple: multiple,
-				values:   make(url.Values),
-				labels:   make(url.Values),
+		if !hasFirst {
+			first, hasFirst = val, true
+		}
+		sel, ok := ss.Attr("selected")
+		if !ok {
+			return
+		}
+		if sel != "" && strings.ToLower(sel) != "selected" {
+			return
+		}
+		fields.Add(name, val)
+		if multiple {
+			return
+		}
+		foundSelected = true
+	})
+	if !multiple && !foundSelected && hasFirst && selectDisplaySize(s) == 1 {
+		fields.Add(name, first)
+	}
+}
+
+// selectDisplaySize returns the number of options a select shows at once,
+// which is the value of its size attribute, or 1 when it's not set or isn't
+// valid.
+func selectDisplaySize(s *goquery.Selection) int {
+	size, err := strconv.Atoi(strings.TrimSpace(s.AttrOr("size", "")))
+	if err != nil || size < 1 {
+		return 1
+	}
+	return size
+}
+
+// fieldOrder maps the name of each form control to its position in the document.
+type fieldOrder map[string]int
+
+// insert adds the name and value to the given values, placing it after every
+// field which appears before it in the document. Names which do not appear in
+// the document are added to the end of the values.
+func (o fieldOrder) insert(values FormValues, name, value string) FormValues {
+	return o.insertAt(values, name, FormValue{Name: name, Value: value})
+}
+
+// insertAt adds the entries to the given values at the position of the control
+// with the given name, the same way as insert.
+func (o fieldOrder) insertAt(values FormValues, control string, entries ...FormValue) FormValues {
+	pos, ok := o[control]
+	if !ok {
+		return append(values, entries...)
+	}
+	i := len(values)
+	for j, v := range values {
+		if o.after(v.Name, pos) {
+			i = j
+			break
+		}
+	}
+	tail := append(append(FormValues{}, entries...), values[i:]...)
+	return append(values[:i], tail...)
+}
+
+// after returns true when the control with the given name appears after the
+// given position in the document, or does not appear in the document.
+func (o fieldOrder) after(name string, pos int) bool {
+	p, ok := o[name]
+	return !ok || p > pos
+}
+
+// multipartParts returns the parts of a multipart body for the values and
+// files, with the files of each file input placed at the position of the
+// input in the document.
+func (o fieldOrder) multipartParts(values FormValues, files MultiFileSet) []multipartPart {
+	parts := multipartParts(values, nil)
+	for _, name := range fileNames(files) {
+		pos, ok := o[name]
+		i := len(parts)
+		for j, p := range parts {
+			if ok && o.after(p.name, pos) {
+				i = j
+				break
 			}
-			var foundSelected bool
-			s.Find(`option`).Each(func(_ int, ss *goquery.Selection) {
-				val, _ := ss.Attr("value")
-				l, _ := ss.Html()
-				selects[name].values.Add(val, strings.TrimSpace(html.UnescapeString(l)))
-				selects[name].labels.Add(strings.TrimSpace(html.UnescapeString(l)), val)
-				if foundSelected {
-					return
-				}
-				sel, ok := ss.Attr("selected")
-				if !ok {
-					return
+		}
+		fp := fileParts(name, files[name])
+		parts = append(parts[:i], append(fp, parts[i:]...)...)
+	}
+	return parts
+}
+
+// formControls returns the submittable elements whose form owner is the given
+// form, in tree order.
+//
+// This follows the form owner rules from the HTML specification. A control
+// with a form attribute belongs to the form element with that id, wherever the
+// control appears in the document, and to no form at all when the id does not
+// match a form. Every other control belongs to its nearest form ancestor.
+// Controls which are disabled, or which appear inside a datalist, are not
+// returned because they never contribute to the form data set.
+func formControls(form *goquery.Selection) *goquery.Selection {
+	if form.Length() == 0 {
+		return form
+	}
+	owner := form.Get(0)
+	root := owner
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	ids := make(map[string]*nethtml.Node)
+	indexIds(root, ids)
+
+	doc := goquery.NewDocumentFromNode(root)
+	return doc.Find("input,button,select,textarea").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		n := s.Get(0)
+		return formOwner(n, ids) == owner && !isControlDisabled(n) && !hasAncestor(n, atom.Datalist)
+	})
+}
+
+// controlNotFound returns an ElementNotFound error for the form control with
+// the given name.
+func controlNotFound(name, msg string, a ...interface{}) error {
+	return errors.NewElementNotFound(msg, a...).With(errors.Fields{Field: name})
+}
+
+// invalidControlValue returns an InvalidFormValue error for the form control
+// with the given name.
+func invalidControlValue(name, msg string, a ...interface{}) error {
+	return errors.NewInvalidFormValue(msg, a...).With(errors.Fields{Field: name})
+}
+
+// formOwner returns the form element which owns the given control, or nil
+// when the control is not associated with any form.
+func formOwner(n *nethtml.Node, ids map[string]*nethtml.Node) *nethtml.Node {
+	if id, ok := nodeAttr(n, "form"); ok {
+		if f, ok := ids[id]; ok && f.DataAtom == atom.Form {
+			return f
+		}
+		return nil
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == nethtml.ElementNode && p.DataAtom == atom.Form {
+			return p
+		}
+	}
+	return parserForm(n)
+}
+
+// parserForm returns the form which the parser associated the control with
+// when the form was left empty in a table, or nil.
+//
+// A form opened between the rows of a table, such as in
+// <table><form><tr><td><input>, can't hold the rows, so the parser closes the
+// form straight away. Web browsers still associate the controls which follow
+// it in the table with the form, because the parser keeps pointing at the
+// form until its end tag. The end tag isn't kept in the tree, so the controls
+// which follow the form anywhere in the table belong to it.
+func parserForm(n *nethtml.Node) *nethtml.Node {
+	for t := n.Parent; t != nil; t = t.Parent {
+		if t.Type != nethtml.ElementNode || t.DataAtom != atom.Table {
+			continue
+		}
+		var form *nethtml.Node
+		var walk func(p *nethtml.Node) bool
+		walk = func(p *nethtml.Node) bool {
+			for c := p.FirstChild; c != nil; c = c.NextSibling {
+				if c == n {
+					return true
 				}
-				if sel != "" && strings.ToLower(sel) != "selected" {
-					return
+				if form == nil && c.Type == nethtml.ElementNode && c.DataAtom == atom.Form && isTablePart(p) {
+					form = c
 				}
-				fields.Add(name, val)
-				if multiple {
-					return
+				if walk(c) {
+					return true
 				}
-				foundSelected = true
-			})
+			}
+			return false
 		}
-	})
+		if walk(t) && form != nil {
+			return form
+		}
+	}
+	return nil
+}
 
-	return fields, buttons, checkboxs, selects, files
+// isTablePart returns true for the elements which hold the rows of a table,
+// where the parser leaves the forms opened between the rows.
+func isTablePart(n *nethtml.Node) bool {
+	switch n.DataAtom {
+	case atom.Table, atom.Tbody, atom.Thead, atom.Tfoot, atom.Tr:
+		return n.Type == nethtml.ElementNode
+	}
+	return false
+}
+
+// isControlDisabled returns true when the control has the disabled attribute,
+// or when it's a descendant of a disabled fieldset. Controls inside the first
+// legend of a disabled fieldset remain enabled.
+func isControlDisabled(n *nethtml.Node) bool {
+	if _, ok := nodeAttr(n, "disabled"); ok {
+		return true
+	}
+	for child, p := n, n.Parent; p != nil; child, p = p, p.Parent {
+		if p.Type != nethtml.ElementNode || p.DataAtom != atom.Fieldset {
+			continue
+		}
+		if _, ok := nodeAttr(p, "disabled"); !ok {
+			continue
+		}
+		if child.DataAtom != atom.Legend || firstLegend(p) != child {
+			return true
+		}
+	}
+	return false
+}
+
+// isOptionDisabled returns true when the option, or the optgroup containing
+// it, has the disabled attribute.
+func isOptionDisabled(s *goquery.Selection) bool {
+	n := s.Get(0)
+	if _, ok := nodeAttr(n, "disabled"); ok {
+		return true
+	}
+	if p := n.Parent; p != nil && p.DataAtom == atom.Optgroup {
+		_, ok := nodeAttr(p, "disabled")
+		return ok
+	}
+	return false
+}
+
+// controlType returns the lower cased type of an input or button element,
+// taking the default type of each element into account.
+func controlType(s *goquery.Selection) string {
+	t, ok := s.Attr("type")
+	t = strings.ToLower(t)
+	if s.Is("button") && (!ok || (t != "reset" && t != "button")) {
+		return "submit"
+	}
+	return t
+}
+
+// hasDirname returns true when the control supports the dirname attribute.
+func hasDirname(s *goquery.Selection, t string) bool {
+	if s.Is("textarea") {
+		return true
+	}
+	switch t {
+	case "", "text", "search", "hidden", "tel", "url", "email", "password":
+		return true
+	}
+	return false
+}
+
+// firstLegend returns the first legend element child of the given fieldset.
+func firstLegend(fieldset *nethtml.Node) *nethtml.Node {
+	for c := fieldset.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && c.DataAtom == atom.Legend {
+			return c
+		}
+	}
+	return nil
+}
+
+// hasAncestor returns true when one of the ancestors of n is the given element.
+func hasAncestor(n *nethtml.Node, a atom.Atom) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == nethtml.ElementNode && p.DataAtom == a {
+			return true
+		}
+	}
+	return false
+}
+
+// indexIds maps the id attribute of every element below n to the first element
+// in tree order that uses it.
+func indexIds(n *nethtml.Node, ids map[string]*nethtml.Node) {
+	if n.Type == nethtml.ElementNode {
+		if id, ok := nodeAttr(n, "id"); ok {
+			if _, seen := ids[id]; !seen {
+				ids[id] = n
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		indexIds(c, ids)
+	}
+}
+
+// nodeAttr returns the value of the named attribute of n.
+func nodeAttr(n *nethtml.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
 }
 
 type selects map[string]selectOptions
@@ -3,6 +3,7 @@ package browser
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -14,6 +15,8 @@ import (
 	"strings"
 
 	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	surferrors "github.com/headzoo/surf/errors"
 	"github.com/headzoo/surf/jar"
@@ -68,10 +71,11 @@ func TestBrowserFormDefaultNotSelected(t *testing.T) {
 
 	ut.AssertEquals(false, f.(*Form).selects["count"].multiple)
 
-	// Initial state should not have any radio or checkbox inputs selected
+	// Initial state should not have any radio or checkbox inputs selected,
+	// and the first option of the select is selected
 	// submit with second button
 	err = f.Click("submit2")
-	ut.AssertEquals("age=&submit2=submitted2", string(bow.body))
+	ut.AssertEquals("age=&count=&submit2=submitted2", string(bow.state.Body))
 
 	// Change text intput for age
 	// submit with first button
@@ -79,51 +83,51 @@ func TestBrowserFormDefaultNotSelected(t *testing.T) {
 	ut.AssertNil(err)
 	err = f.Click("submit1")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&submit1=submitted1`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&submit1=submitted1`, string(bow.state.Body))
 
 	// gender does not exist in the form, so Set() is required to add it to the form
 	err = f.Set("gender", "male")
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=male&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=male&submit2=submitted2`, string(bow.state.Body))
 
 	// Change gender
 	err = f.Input("gender", "female")
 	ut.AssertNil(err)
 	err = f.Click("submit1")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&submit1=submitted1`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&submit1=submitted1`, string(bow.state.Body))
 
 	err = f.Set("option1", "on")
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&option1=on&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&option1=on&submit2=submitted2`, string(bow.state.Body))
 
 	err = f.Set("option2", "on")
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&option1=on&option2=on&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&option1=on&option2=on&submit2=submitted2`, string(bow.state.Body))
 
 	// uncheck option1
 	f.Remove("option1")
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&option2=on&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&option2=on&submit2=submitted2`, string(bow.state.Body))
 
 	// uncheck option2
 	f.Remove("option2")
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&submit2=submitted2`, string(bow.state.Body))
 
 	err = f.Check("option1")
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&option1=on&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&option1=on&submit2=submitted2`, string(bow.state.Body))
 	b, err := f.IsChecked("option1")
 	ut.AssertNil(err)
 	ut.AssertEquals(true, b)
@@ -133,14 +137,14 @@ func TestBrowserFormDefaultNotSelected(t *testing.T) {
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&option1=on&option2=on&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&option1=on&option2=on&submit2=submitted2`, string(bow.state.Body))
 
 	// uncheck option1
 	err = f.UnCheck("option1")
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&option2=on&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&option2=on&submit2=submitted2`, string(bow.state.Body))
 	b, err = f.IsChecked("option1")
 	ut.AssertNil(err)
 	ut.AssertEquals(false, b)
@@ -150,31 +154,34 @@ func TestBrowserFormDefaultNotSelected(t *testing.T) {
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&gender=female&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=&gender=female&submit2=submitted2`, string(bow.state.Body))
 	_, err = f.IsChecked("option3")
-	ut.AssertEquals(surferrors.NewElementNotFound("No checkbox found with name 'option3'."), err)
+	ut.AssertEquals(surferrors.NewElementNotFound(
+		"No checkbox found with name 'option3'.").With(surferrors.Fields{Field: "option3"}), err)
 
 	// select count by label
 	err = f.SelectByOptionLabel("count", "Two")
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&count=2&gender=female&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=2&gender=female&submit2=submitted2`, string(bow.state.Body))
 
 	// select multi count by label
 	err = f.SelectByOptionLabel("count", "Two", "Three")
-	ut.AssertEquals(surferrors.NewElementNotFound("The select element with name 'count' is not a select miltiple."), err)
+	ut.AssertEquals(surferrors.NewElementNotFound(
+		"The select element with name 'count' is not a select miltiple.").With(surferrors.Fields{Field: "count"}), err)
 
 	// select count by value
 	err = f.SelectByOptionValue("count", "5")
 	ut.AssertNil(err)
 	err = f.Click("submit2")
 	ut.AssertNil(err)
-	ut.AssertEquals(`age=55&count=5&gender=female&submit2=submitted2`, string(bow.body))
+	ut.AssertEquals(`age=55&count=5&gender=female&submit2=submitted2`, string(bow.state.Body))
 
 	// select multi count by value
 	err = f.SelectByOptionValue("count", "5", "3")
-	ut.AssertEquals(surferrors.NewElementNotFound("The select element with name 'count' is not a select miltiple."), err)
+	ut.AssertEquals(surferrors.NewElementNotFound(
+		"The select element with name 'count' is not a select miltiple.").With(surferrors.Fields{Field: "count"}), err)
 }
 
 func TestBrowserFormSelected(t *testing.T) {
@@ -211,7 +218,7 @@ func TestBrowserFormSelected(t *testing.T) {
 	// Initial state should have defaults selected
 	err = f.Submit()
 	ut.AssertNil(err)
-	ut.AssertEquals("count=2&submit=submitted", string(bow.body))
+	ut.AssertEquals("count=2&submit=submitted", string(bow.state.Body))
 }
 
 func TestBrowserFormDefaultsSelected(t *testing.T) {
@@ -257,7 +264,7 @@ func TestBrowserFormDefaultsSelected(t *testing.T) {
 	// Initial state should have defaults selected
 	err = f.Submit()
 	ut.AssertNil(err)
-	ut.AssertEquals("count=3&gender=female&option2=on&submit=submitted", string(bow.body))
+	ut.AssertEquals("count=3&gender=female&option2=on&submit=submitted", string(bow.state.Body))
 
 	val, err := f.Value("option2")
 	ut.AssertNil(err)
@@ -316,28 +323,28 @@ func TestBrowserFormSelectMultiple(t *testing.T) {
 	// Initial state should have defaults selected
 	err = f.Submit()
 	ut.AssertNil(err)
-	ut.AssertEquals("count=1&count=3&submit=submitted", string(bow.body))
+	ut.AssertEquals("count=1&count=3&submit=submitted", string(bow.state.Body))
 
 	// select multi count by value
 	err = f.SelectByOptionValue("count", "5", "1")
 	ut.AssertNil(err)
 	err = f.Submit()
 	ut.AssertNil(err)
-	ut.AssertEquals(`count=5&count=1&submit=submitted`, string(bow.body))
+	ut.AssertEquals(`count=5&count=1&submit=submitted`, string(bow.state.Body))
 
 	// select multi count by label
 	err = f.SelectByOptionLabel("count", "Two", "Three")
 	ut.AssertNil(err)
 	err = f.Submit()
 	ut.AssertNil(err)
-	ut.AssertEquals(`count=2&count=3&submit=submitted`, string(bow.body))
+	ut.AssertEquals(`count=2&count=3&submit=submitted`, string(bow.state.Body))
 
 	// select multi count by label
 	err = f.RemoveValue("count", "2")
 	ut.AssertNil(err)
 	err = f.Submit()
 	ut.AssertNil(err)
-	ut.AssertEquals(`count=3&submit=submitted`, string(bow.body))
+	ut.AssertEquals(`count=3&submit=submitted`, string(bow.state.Body))
 }
 
 func TestBrowserFormClickByValue(t *testing.T) {
@@ -409,6 +416,577 @@ func TestSubmitMultipart(t *testing.T) {
 	ut.AssertContains(fmt.Sprintf("profile.png=%s", url.QueryEscape(image)), bow.Body())
 }
 
+func TestBrowserFormOwner(t *testing.T) {
+	ts := setupTestServer(`<!doctype html>
+<html>
+	<head>
+		<title>Form owner</title>
+	</head>
+	<body>
+		<input type="text" name="before" value="b" form="signup" />
+		<form method="post" action="/" id="signup" name="default">
+			<input type="text" name="user" value="sean" />
+			<input type="text" name="other" value="o" form="search" />
+			<input type="text" name="missing" value="m" form="nope" />
+			<fieldset disabled>
+				<legend><input type="text" name="legend" value="l" /></legend>
+				<input type="text" name="fieldset" value="f" />
+				<legend><input type="text" name="legend2" value="l2" /></legend>
+			</fieldset>
+			<fieldset>
+				<input type="text" name="enabled" value="e" />
+			</fieldset>
+			<input type="text" name="bare" value="x" disabled />
+			<input type="checkbox" name="agree" value="yes" checked />
+			<datalist id="colors"><input type="text" name="list" value="red" /></datalist>
+			<select name="color">
+				<option value="red" selected disabled>Red</option>
+				<optgroup label="More" disabled>
+					<option value="green" selected>Green</option>
+				</optgroup>
+				<option value="blue">Blue</option>
+			</select>
+			<select name="box" size="3"><option value="small">Small</option></select>
+			<input type="reset" name="reset" value="r" />
+			<input type="button" name="button" value="b" />
+			<button name="go" value="1">Go</button>
+		</form>
+		<form method="post" action="/" id="search"></form>
+		<input type="hidden" name="after" form="signup" value="a" />
+		<select name="size" form="signup"><option value="xl" selected>XL</option></select>
+		<table>
+			<form method="post" action="/" id="table">
+			<tr><td><input type="text" name="a" value="1" /></td></tr>
+			</form>
+		</table>
+		<input type="text" name="outside" value="o" />
+	</body>
+</html>`, t)
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+
+	f, err := bow.Form("#signup")
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("after=a&agree=yes&before=b&color=blue&enabled=e&go=1&legend=l&size=xl&user=sean", string(bow.state.Body))
+
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	f, err = bow.Form("#search")
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("other=o", string(bow.state.Body))
+
+	// The parser moves the form out of the way of the table rows, but the
+	// controls which follow it in the table still belong to it.
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	f, err = bow.Form("#table")
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("a=1", string(bow.state.Body))
+}
+
+func TestBrowserFormFieldOrder(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.RawQuery == "" {
+			fmt.Fprint(w, `<!doctype html>
+<html>
+	<body>
+		<form method="post" action="/" name="default">
+			<input type="text" name="zeta" value="z" />
+			<input type="checkbox" name="mid" value="m" />
+			<select name="alpha"><option value="a" selected>A</option><option value="b">B</option></select>
+			<input type="submit" name="go" value="1" />
+			<input type="text" name="beta" value="b" />
+			<input type="text" name="zeta" value="z2" />
+		</form>
+		<form method="get" action="/" name="search">
+			<input type="text" name="q" value="surf" />
+			<input type="text" name="a" value="1" />
+		</form>
+	</body>
+</html>`)
+			return
+		}
+		if r.Method == "GET" {
+			fmt.Fprint(w, r.URL.RawQuery)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	f, err := bow.Form("[name='default']")
+	ut.AssertNil(err)
+
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("zeta=z&alpha=a&go=1&beta=b&zeta=z2", string(bow.state.Body))
+
+	err = f.Check("mid")
+	ut.AssertNil(err)
+	err = f.SelectByOptionValue("alpha", "b")
+	ut.AssertNil(err)
+	err = f.Set("extra", "e")
+	ut.AssertNil(err)
+	err = f.Input("beta", "B")
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("zeta=z&mid=m&alpha=b&go=1&beta=B&zeta=z2&extra=e", string(bow.state.Body))
+
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	f, err = bow.Form("[name='search']")
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("q=surf&a=1", string(bow.state.Body))
+}
+
+func TestSubmitMultipartFiles(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `<!doctype html>
+<html>
+	<body>
+		<form method="post" action="/" name="default" enctype="multipart/form-data">
+			<input type="file" name="photos" accept="image/*,.txt" multiple />
+			<input type="file" name="avatar" accept=".png" />
+			<input type="file" name="empty" />
+		</form>
+	</body>
+</html>`)
+			return
+		}
+		r.ParseMultipartForm(1024 * 1024)
+		for _, name := range []string{"photos", "avatar", "empty"} {
+			for _, fh := range r.MultipartForm.File[name] {
+				fmt.Fprintf(w, "%s:%s:%s;", name, fh.Filename, fh.Header.Get("Content-Type"))
+			}
+		}
+	}))
+	defer ts.Close()
+
+	tmp, err := ioutil.TempFile("", "surf-*.txt")
+	ut.AssertNil(err)
+	defer os.Remove(tmp.Name())
+	tmp.WriteString("hello")
+	tmp.Close()
+
+	bow := newBrowser()
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	sub, err := bow.Form("[name='default']")
+	ut.AssertNil(err)
+	f := sub.(*Form)
+
+	imgData, err := base64.StdEncoding.DecodeString(image)
+	ut.AssertNil(err)
+	disk, err := OpenFile(tmp.Name())
+	ut.AssertNil(err)
+	_, err = OpenFile(os.TempDir())
+	_, ok := err.(surferrors.Error)
+	ut.AssertTrue(ok)
+
+	err = f.AddFile("photos", NewFile("pixel", bytes.NewReader(imgData)))
+	ut.AssertNil(err)
+	err = f.AddFile("photos", disk)
+	ut.AssertNil(err)
+	err = f.AddFile("photos", NewFile("notes.pdf", strings.NewReader("%PDF-1.4")))
+	_, ok = err.(surferrors.InvalidFormValue)
+	ut.AssertTrue(ok)
+
+	err = f.AddFile("avatar", NewFile("a.png", bytes.NewReader(imgData)).SetContentType("image/x-custom"))
+	ut.AssertNil(err)
+	err = f.AddFile("avatar", NewFile("b.png", bytes.NewReader(imgData)))
+	_, ok = err.(surferrors.InvalidFormValue)
+	ut.AssertTrue(ok)
+	err = f.SetFiles("avatar", NewFile("b.png", nil), NewFile("c.png", nil))
+	_, ok = err.(surferrors.InvalidFormValue)
+	ut.AssertTrue(ok)
+	err = f.AddFile("missing", NewFile("a.png", nil))
+	_, ok = err.(surferrors.ElementNotFound)
+	ut.AssertTrue(ok)
+
+	err = f.Submit()
+	ut.AssertNil(err)
+	expected := fmt.Sprintf("photos:pixel:image/png;photos:%s:text/plain;avatar:a.png:image/x-custom;",
+		filepath.Base(tmp.Name()))
+	ut.AssertEquals(expected, string(bow.state.Body))
+}
+
+func TestSubmitMultipartOrder(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `<!doctype html>
+<html>
+	<body>
+		<form method="post" action="/" name="default" enctype="multipart/form-data">
+			<input type="text" name="title" value="report" />
+			<input type="file" name="doc" />
+			<input type="text" name="author" value="me" />
+			<input type="file" name="empty" />
+			<input type="submit" name="send" value="Send" />
+		</form>
+	</body>
+</html>`)
+			return
+		}
+		mr, err := r.MultipartReader()
+		if err != nil {
+			return
+		}
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			fmt.Fprintf(w, "%s:%s;", p.FormName(), p.FileName())
+		}
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	f, err := bow.Form("[name='default']")
+	ut.AssertNil(err)
+	err = f.File("doc", "a.txt", strings.NewReader("hello"))
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("title:;doc:a.txt;author:;empty:;send:;", string(bow.state.Body))
+}
+
+func TestPostMultipartFileSet(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, "<html></html>")
+			return
+		}
+		r.ParseMultipartForm(1024)
+		fh := r.MultipartForm.File["doc"][0]
+		f, _ := fh.Open()
+		b, _ := ioutil.ReadAll(f)
+		fmt.Fprintf(w, "%s:%s:%s", r.MultipartForm.Value["title"][0], fh.Filename, b)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	err = bow.PostMultipart(ts.URL, url.Values{"title": {"report"}},
+		FileSet{"doc": NewFile("a.txt", strings.NewReader("hello"))})
+	ut.AssertNil(err)
+	ut.AssertEquals("report:a.txt:hello", string(bow.state.Body))
+}
+
+func TestPostMultipartRedirect(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+			return
+		}
+		if r.Method == "GET" {
+			fmt.Fprint(w, "<html></html>")
+			return
+		}
+		r.ParseMultipartForm(1024)
+		var out []string
+		for _, name := range []string{"path", "reader"} {
+			fh := r.MultipartForm.File[name][0]
+			f, _ := fh.Open()
+			b, _ := ioutil.ReadAll(f)
+			out = append(out, fmt.Sprintf("%s:%s", fh.Filename, b))
+		}
+		fmt.Fprint(w, strings.Join(out, ";"))
+	}))
+	defer ts.Close()
+
+	tmp, err := ioutil.TempFile("", "surf")
+	ut.AssertNil(err)
+	defer os.Remove(tmp.Name())
+	tmp.WriteString("from disk")
+	tmp.Close()
+	disk, err := OpenFile(tmp.Name())
+	ut.AssertNil(err)
+
+	// The body is sent again to the location of a 307 redirect.
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{FollowRedirects: true})
+	ut.AssertNil(bow.Open(ts.URL))
+	err = bow.PostMultipart(ts.URL+"/old", url.Values{"title": {"report"}},
+		FileSet{"path": disk, "reader": NewFile("a.txt", strings.NewReader("hello"))})
+	ut.AssertNil(err)
+	ut.AssertEquals(200, bow.StatusCode())
+	ut.AssertEquals(ts.URL+"/new", bow.Url().String())
+	ut.AssertEquals(filepath.Base(tmp.Name())+":from disk;a.txt:hello", string(bow.state.Body))
+
+	// Data which can't be read again stops on the redirect.
+	err = bow.PostMultipart(ts.URL+"/old", url.Values{"title": {"report"}},
+		FileSet{"path": disk, "reader": NewFile("a.txt", io.MultiReader(strings.NewReader("hello")))})
+	ut.AssertNil(err)
+	ut.AssertEquals(http.StatusTemporaryRedirect, bow.StatusCode())
+}
+
+func TestSubmitMultipartStreaming(t *testing.T) {
+	ut.Run(t)
+	var lengths []int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `<!doctype html>
+<html>
+	<body>
+		<form method="post" action="/" name="default" enctype="multipart/form-data">
+			<input type="text" name="comment" value="big" />
+			<input type="file" name="data" />
+		</form>
+	</body>
+</html>`)
+			return
+		}
+		lengths = append(lengths, r.ContentLength)
+		r.ParseMultipartForm(1024)
+		fh := r.MultipartForm.File["data"][0]
+		f, _ := fh.Open()
+		n, _ := io.Copy(ioutil.Discard, f)
+		fmt.Fprintf(w, "%s:%d", r.MultipartForm.Value["comment"][0], n)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	var sent, total int64
+	bow.SetUploadProgress(func(s, t int64) {
+		sent, total = s, t
+	})
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	f, err := bow.Form("[name='default']")
+	ut.AssertNil(err)
+
+	// The size of a bytes.Reader is known, so Content-Length is sent.
+	data := bytes.Repeat([]byte("surf"), 1024*1024)
+	err = f.File("data", "data.bin", bytes.NewReader(data))
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("big:4194304", string(bow.state.Body))
+	ut.AssertEquals(lengths[0], total)
+	ut.AssertEquals(total, sent)
+
+	// The size of a pipe is unknown, so the body is chunked.
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	f, err = bow.Form("[name='default']")
+	ut.AssertNil(err)
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 1024; i++ {
+			pw.Write(data[:4096])
+		}
+		pw.Close()
+	}()
+	err = f.File("data", "data.bin", pr)
+	ut.AssertNil(err)
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("big:4194304", string(bow.state.Body))
+	ut.AssertEquals(int64(-1), lengths[1])
+	ut.AssertEquals(int64(-1), total)
+}
+
+func TestMultipartFileChanged(t *testing.T) {
+	ut.Run(t)
+	tmp, err := ioutil.TempFile("", "surf")
+	ut.AssertNil(err)
+	defer os.Remove(tmp.Name())
+	tmp.WriteString("hello")
+	tmp.Close()
+
+	file, err := OpenFile(tmp.Name())
+	ut.AssertNil(err)
+	body, _, err := newMultipartBody([]multipartPart{{name: "doc", file: file, isFile: true}})
+	ut.AssertNil(err)
+	ut.AssertTrue(body.Size() > 5)
+
+	// The file grows after the Content-Length was found.
+	err = ioutil.WriteFile(tmp.Name(), []byte("hello world"), 0600)
+	ut.AssertNil(err)
+	_, err = ioutil.ReadAll(body)
+	_, ok := err.(surferrors.InvalidFormValue)
+	ut.AssertTrue(ok)
+	body.Close()
+}
+
+func TestBrowserFormEncoding(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.RawQuery == "" {
+			fmt.Fprint(w, `<!doctype html>
+<html>
+	<body>
+		<form method="post" action="/" name="textarea">
+			<textarea name="comment" value="ignored">
+Line one
+Line two</textarea>
+			<input type="text" name="q" value="שלום" dirname="q.dir" dir="auto" />
+			<textarea name="note" dirname="note.dir"></textarea>
+		</form>
+		<form method="post" action="/" name="sjis" accept-charset="bogus Shift_JIS">
+			<input type="text" name="name" value="日本" />
+		</form>
+		<form method="get" action="/" name="latin1" accept-charset="ISO-8859-1">
+			<input type="text" name="name" value="café 日" />
+		</form>
+		<form method="post" action="/" name="plain" enctype="TEXT/PLAIN">
+			<input type="text" name="a" value="1 2" />
+			<input type="text" name="b" value="x&y" />
+		</form>
+	</body>
+</html>`)
+			return
+		}
+		if r.Method == "GET" {
+			fmt.Fprint(w, r.URL.RawQuery)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("Content-Type"), body)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	submit := func(name string) string {
+		err := bow.Open(ts.URL)
+		ut.AssertNil(err)
+		f, err := bow.Form("[name='" + name + "']")
+		ut.AssertNil(err)
+		err = f.Submit()
+		ut.AssertNil(err)
+		return string(bow.state.Body)
+	}
+
+	ut.AssertEquals(
+		"application/x-www-form-urlencoded|comment=Line+one%0D%0ALine+two&q=%D7%A9%D7%9C%D7%95%D7%9D&q.dir=rtl&note=&note.dir=ltr",
+		submit("textarea"))
+	ut.AssertEquals("application/x-www-form-urlencoded|name=%93%FA%96%7B", submit("sjis"))
+	ut.AssertEquals("name=caf%E9+%26%2326085%3B", submit("latin1"))
+	ut.AssertEquals("text/plain|a=1 2\r\nb=x&y\r\n", submit("plain"))
+}
+
+type fillAddress struct {
+	City    string `form:"city"`
+	Country string `form:"country"`
+}
+
+type fillContact struct {
+	Email string `form:"email"`
+}
+
+type fillSignup struct {
+	fillContact
+	Name      string      `form:"name"`
+	Age       int         `form:"age"`
+	Bio       string      `form:"bio"`
+	Terms     bool        `form:"terms"`
+	Gender    string      `form:"gender"`
+	Interests []string    `form:"interests"`
+	Colors    []string    `form:"colors"`
+	Address   fillAddress `form:"address"`
+	Phone     string      `form:"phone,omitempty"`
+	Internal  string      `form:"-"`
+	Untagged  string
+	Nickname  string `form:"nickname"`
+}
+
+func TestBrowserFormFill(t *testing.T) {
+	ts := setupTestServer(`<!doctype html>
+<html>
+	<body>
+		<form method="post" action="/" name="default">
+			<input type="email" name="email" />
+			<input type="text" name="name" />
+			<input type="number" name="age" />
+			<textarea name="bio"></textarea>
+			<input type="checkbox" name="terms" value="yes" />
+			<input type="radio" name="gender" value="male" checked />
+			<input type="radio" name="gender" value="female" />
+			<input type="checkbox" name="interests" value="go" />
+			<input type="checkbox" name="interests" value="html" />
+			<input type="checkbox" name="interests" value="css" />
+			<select name="colors" multiple>
+				<option value="r">Red</option>
+				<option value="g">Green</option>
+				<option value="b">Blue</option>
+			</select>
+			<input type="text" name="address[city]" />
+			<select name="address[country]">
+				<option value="us">United States</option>
+				<option value="ca">Canada</option>
+			</select>
+			<input type="text" name="phone" value="555" />
+		</form>
+	</body>
+</html>`, t)
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	sub, err := bow.Form("[name='default']")
+	ut.AssertNil(err)
+	f := sub.(*Form)
+
+	err = f.Fill(&fillSignup{
+		fillContact: fillContact{Email: "sean@example.com"},
+		Name:        "Sean",
+		Age:         42,
+		Bio:         "Hi",
+		Terms:       true,
+		Gender:      "female",
+		Interests:   []string{"go", "css"},
+		Colors:      []string{"Green", "Blue"},
+		Address:     fillAddress{City: "Berlin", Country: "ca"},
+		Internal:    "secret",
+		Untagged:    "none",
+		Nickname:    "headzoo",
+	})
+	_, ok := err.(surferrors.ElementNotFound)
+	ut.AssertTrue(ok)
+	ut.AssertContains("nickname", err.Error())
+
+	err = f.Submit()
+	ut.AssertNil(err)
+	ut.AssertEquals("address%5Bcity%5D=Berlin&address%5Bcountry%5D=ca&age=42&bio=Hi&colors=g&colors=b&email=sean%40example.com&gender=female&interests=go&interests=css&name=Sean&phone=555&terms=yes", string(bow.state.Body))
+
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	sub, err = bow.Form("[name='default']")
+	ut.AssertNil(err)
+	err = sub.(*Form).Fill(struct {
+		Gender string `form:"gender"`
+	}{"other"})
+	_, ok = err.(surferrors.InvalidFormValue)
+	ut.AssertTrue(ok)
+}
+
 func setupTestServer(html string, t *testing.T) *httptest.Server {
 	ut.Run(t)
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,803 @@
+package browser
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dop251/goja"
+	"github.com/headzoo/surf/errors"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// JavaScriptTimeout is the longest the scripts of a page may run for,
+// including their timers and event listeners, before they're interrupted.
+var JavaScriptTimeout = 10 * time.Second
+
+// JavaScriptMaxTimers is the most setTimeout and setInterval callbacks run
+// for a page, which stops pages which poll with timers from running forever.
+var JavaScriptMaxTimers = 1000
+
+// JavaScriptMaxNavigations is the most pages scripts may load in a row, which
+// stops pages which reload themselves from loading forever.
+var JavaScriptMaxNavigations = 10
+
+// jsRuntime runs the scripts of a page.
+//
+// Scripts run against a DOM backed by the nodes of the page document, so the
+// changes they make are seen by Find, Forms and everything else reading the
+// page. Timers run on a virtual clock once the page has loaded, so nothing
+// is waited on. Setting the location or submitting a form stops the scripts,
+// and the page they navigated to is loaded once they've stopped.
+type jsRuntime struct {
+	bow *Browser
+	vm  *goja.Runtime
+	dom *goquery.Document
+	url *url.URL
+
+	// wrappers and nodes map the nodes of the document to the objects which
+	// represent them in scripts, and back.
+	wrappers      map[*nethtml.Node]*goja.Object
+	nodes         map[*goja.Object]*nethtml.Node
+	nodeProto     *goja.Object
+	elementProto  *goja.Object
+	documentProto *goja.Object
+	location      *goja.Object
+	styles        map[*nethtml.Node]*goja.Object
+
+	// listeners holds the event listeners of each node by event type. The
+	// listeners of the window are held by the nil node.
+	listeners map[*nethtml.Node]map[string][]goja.Value
+
+	// executed holds the script elements which have already run, current
+	// is the script element which is running, and cursor is the node that
+	// document.write inserts after.
+	executed map[*nethtml.Node]bool
+	current  *nethtml.Node
+	cursor   *nethtml.Node
+	loading  bool
+
+	timers  []*jsTimer
+	timerId int
+	now     int64
+	ran     int
+
+	// handled counts the event listeners and javascript: URLs which have run.
+	handled int
+
+	// navigation loads the page the scripts navigated to.
+	navigation func() error
+
+	stopped  bool
+	failures []error
+}
+
+// jsTimer is a callback set with setTimeout or setInterval.
+type jsTimer struct {
+	id       int
+	due      int64
+	interval int64
+	fn       goja.Value
+	args     []goja.Value
+}
+
+// newJSRuntime returns a runtime for the current page of the browser.
+func newJSRuntime(bow *Browser) *jsRuntime {
+	u := *bow.Url()
+	rt := &jsRuntime{
+		bow:       bow,
+		vm:        goja.New(),
+		dom:       bow.state.Dom,
+		url:       &u,
+		wrappers:  make(map[*nethtml.Node]*goja.Object),
+		nodes:     make(map[*goja.Object]*nethtml.Node),
+		styles:    make(map[*nethtml.Node]*goja.Object),
+		listeners: make(map[*nethtml.Node]map[string][]goja.Value),
+		executed:  make(map[*nethtml.Node]bool),
+	}
+	rt.initDOM()
+	rt.initWindow()
+	rt.initNetwork()
+	return rt
+}
+
+// root returns the document node of the page.
+func (rt *jsRuntime) root() *nethtml.Node {
+	return rt.dom.Get(0)
+}
+
+// runPage runs the scripts of the page in document order, with deferred
+// scripts last, then fires the DOMContentLoaded and load events and runs the
+// timers.
+func (rt *jsRuntime) runPage() {
+	defer rt.limit()()
+	var scripts, deferred []*nethtml.Node
+	walkElements(rt.root(), func(n *nethtml.Node) {
+		if n.DataAtom == atom.Script {
+			scripts = append(scripts, n)
+		}
+	})
+	rt.loading = true
+	for _, n := range scripts {
+		_, src := nodeAttr(n, "src")
+		if _, ok := nodeAttr(n, "defer"); ok && src {
+			deferred = append(deferred, n)
+			continue
+		}
+		rt.runElement(n)
+	}
+	for _, n := range deferred {
+		rt.runElement(n)
+	}
+	rt.loading = false
+	if !rt.done() {
+		rt.dispatch(rt.root(), rt.newEvent("DOMContentLoaded", true))
+	}
+	if !rt.done() {
+		rt.dispatch(nil, rt.newEvent("load", false))
+	}
+	rt.runTimers()
+}
+
+// done returns true when the scripts have navigated away from the page, or
+// have been interrupted.
+func (rt *jsRuntime) done() bool {
+	return rt.navigation != nil || rt.stopped
+}
+
+// limit interrupts the scripts once they've run for JavaScriptTimeout. The
+// returned function must be called when they've stopped.
+func (rt *jsRuntime) limit() func() {
+	rt.stopped = false
+	timer := time.AfterFunc(JavaScriptTimeout, func() {
+		rt.vm.Interrupt(errors.New("Scripts ran for longer than %s.", JavaScriptTimeout))
+	})
+	return func() {
+		timer.Stop()
+		rt.vm.ClearInterrupt()
+	}
+}
+
+// runElement runs a script element, fetching its source when it has a src
+// attribute. Elements which aren't classic scripts are skipped, and every
+// element is run once at most.
+func (rt *jsRuntime) runElement(n *nethtml.Node) {
+	if rt.executed[n] || rt.done() {
+		return
+	}
+	rt.executed[n] = true
+	if !isJavaScript(n) {
+		return
+	}
+	name, code := rt.url.String(), nodeText(n)
+	if src, ok := nodeAttr(n, "src"); ok {
+		u, err := rt.resolve(src)
+		if err != nil {
+			rt.fail(err)
+			return
+		}
+		resp, body, err := rt.request("GET", u, nil, nil)
+		if err != nil {
+			rt.fail(err)
+			return
+		}
+		if resp.StatusCode >= 400 {
+			rt.fail(errors.New("Script '%s' could not be loaded: %s", u, resp.Status))
+			return
+		}
+		name, code = u.String(), string(body)
+	}
+
+	current, cursor := rt.current, rt.cursor
+	rt.current, rt.cursor = n, n
+	rt.run(name, code)
+	rt.current, rt.cursor = current, cursor
+}
+
+// isJavaScript returns true when the script element holds a classic script,
+// rather than a module or a data block such as JSON.
+func isJavaScript(n *nethtml.Node) bool {
+	t, ok := nodeAttr(n, "type")
+	if !ok || strings.TrimSpace(t) == "" {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "text/javascript", "application/javascript", "text/ecmascript",
+		"application/ecmascript", "application/x-javascript", "text/x-javascript",
+		"text/jscript", "text/livescript":
+		return true
+	}
+	return false
+}
+
+// run runs the code, recording the exception it throws.
+func (rt *jsRuntime) run(name, code string) goja.Value {
+	v, err := rt.vm.RunScript(name, code)
+	rt.fail(err)
+	return v
+}
+
+// call calls the function, recording the exception it throws. Nothing is
+// done when fn isn't a function.
+func (rt *jsRuntime) call(fn, this goja.Value, args ...goja.Value) goja.Value {
+	f, ok := goja.AssertFunction(fn)
+	if !ok {
+		return goja.Undefined()
+	}
+	v, err := f(this, args...)
+	rt.fail(err)
+	if v == nil {
+		return goja.Undefined()
+	}
+	return v
+}
+
+// fail records an exception thrown by the scripts. Exceptions don't stop the
+// other scripts from running, the same as in a browser, but interrupting the
+// scripts stops all of them.
+func (rt *jsRuntime) fail(err error) {
+	if err == nil {
+		return
+	}
+	if ie, ok := err.(*goja.InterruptedError); ok {
+		if rt.stopped {
+			return
+		}
+		rt.stopped = true
+		// Scripts may be running below the one which was interrupted.
+		rt.vm.Interrupt(ie.Value())
+	}
+	rt.failures = append(rt.failures, err)
+}
+
+// setTimer implements setTimeout and setInterval.
+func (rt *jsRuntime) setTimer(call goja.FunctionCall, repeat bool) goja.Value {
+	delay := call.Argument(1).ToInteger()
+	if delay < 0 {
+		delay = 0
+	}
+	rt.timerId++
+	t := &jsTimer{id: rt.timerId, due: rt.now + delay, interval: -1, fn: call.Argument(0)}
+	if repeat {
+		t.interval = delay
+	}
+	if len(call.Arguments) > 2 {
+		t.args = call.Arguments[2:]
+	}
+	rt.timers = append(rt.timers, t)
+	return rt.vm.ToValue(t.id)
+}
+
+// clearTimer implements clearTimeout and clearInterval.
+func (rt *jsRuntime) clearTimer(call goja.FunctionCall) goja.Value {
+	id := int(call.Argument(0).ToInteger())
+	for i, t := range rt.timers {
+		if t.id == id {
+			rt.timers = append(rt.timers[:i], rt.timers[i+1:]...)
+			break
+		}
+	}
+	return goja.Undefined()
+}
+
+// later runs fn from the timer queue, after the running script.
+func (rt *jsRuntime) later(fn func()) {
+	rt.timerId++
+	rt.timers = append(rt.timers, &jsTimer{
+		id:       rt.timerId,
+		due:      rt.now,
+		interval: -1,
+		fn: rt.vm.ToValue(func(goja.FunctionCall) goja.Value {
+			fn()
+			return goja.Undefined()
+		}),
+	})
+}
+
+// runTimers runs the timers in the order they're due, moving the virtual
+// clock forward to each one, until none are left or JavaScriptMaxTimers
+// callbacks have run.
+func (rt *jsRuntime) runTimers() {
+	for len(rt.timers) > 0 && !rt.done() && rt.ran < JavaScriptMaxTimers {
+		next := 0
+		for i, t := range rt.timers {
+			if t.due < rt.timers[next].due {
+				next = i
+			}
+		}
+		t := rt.timers[next]
+		rt.timers = append(rt.timers[:next], rt.timers[next+1:]...)
+		rt.now = t.due
+		if t.interval >= 0 {
+			t.due += t.interval
+			rt.timers = append(rt.timers, t)
+		}
+		rt.ran++
+		if _, ok := goja.AssertFunction(t.fn); ok {
+			rt.call(t.fn, goja.Undefined(), t.args...)
+		} else {
+			rt.run(rt.url.String(), t.fn.String())
+		}
+	}
+}
+
+// request makes a request for the scripts with the client of the browser, so
+// the request shares its cookies and headers, and returns the decoded body.
+func (rt *jsRuntime) request(method string, u *url.URL, header http.Header, body io.Reader) (*http.Response, []byte, error) {
+	req, err := rt.bow.buildRequest(method, u.String(), rt.url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if rt.bow.client == nil {
+		rt.bow.client = rt.bow.buildClient()
+	}
+	resp, err := rt.bow.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := readBody(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+// resolve resolves a URL used by the scripts against the page URL.
+func (rt *jsRuntime) resolve(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	return rt.bow.ResolveUrl(u), nil
+}
+
+// assign loads the page at the URL once the scripts have stopped. Changing
+// only the fragment of the URL doesn't load a page, and javascript: URLs are
+// run instead of loaded.
+func (rt *jsRuntime) assign(raw string) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 11 && strings.EqualFold(raw[:11], "javascript:") {
+		code, err := url.PathUnescape(raw[11:])
+		if err != nil {
+			code = raw[11:]
+		}
+		rt.handled++
+		rt.run(rt.url.String(), code)
+		return
+	}
+	u, err := rt.resolve(raw)
+	if err != nil {
+		rt.fail(err)
+		return
+	}
+	if u.Fragment != "" && sameDocument(u, rt.url) {
+		rt.url = u
+		return
+	}
+	ref := rt.url
+	rt.navigate(func() error {
+		return rt.bow.httpGET(u, ref)
+	})
+}
+
+// navigate sets the function which loads the page the scripts navigated to,
+// replacing any earlier navigation.
+func (rt *jsRuntime) navigate(fn func() error) {
+	rt.navigation = fn
+}
+
+// follow loads the page the scripts navigated to, if any.
+func (rt *jsRuntime) follow() error {
+	nav := rt.navigation
+	if nav == nil {
+		return nil
+	}
+	rt.navigation = nil
+	bow := rt.bow
+	if bow.jsNavigations >= JavaScriptMaxNavigations {
+		return errors.New("Scripts loaded more than %d pages in a row.", JavaScriptMaxNavigations)
+	}
+	bow.jsNavigations++
+	defer func() { bow.jsNavigations-- }()
+	return nav()
+}
+
+// sameDocument returns true when the URLs differ only by their fragment.
+func sameDocument(a, b *url.URL) bool {
+	x, y := *a, *b
+	x.Fragment, x.RawFragment = "", ""
+	y.Fragment, y.RawFragment = "", ""
+	return x.String() == y.String()
+}
+
+// define defines an accessor property of o, which is read-only when set is nil.
+func (rt *jsRuntime) define(o *goja.Object, name string, get func() goja.Value, set func(goja.Value)) {
+	getter := rt.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return get()
+	})
+	var setter goja.Value
+	if set != nil {
+		setter = rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			set(call.Argument(0))
+			return goja.Undefined()
+		})
+	}
+	o.DefineAccessorProperty(name, getter, setter, goja.FLAG_TRUE, goja.FLAG_TRUE)
+}
+
+// initWindow defines the globals of the window.
+func (rt *jsRuntime) initWindow() {
+	vm := rt.vm
+	window := vm.GlobalObject()
+	for _, name := range []string{"window", "self", "top", "parent", "frames"} {
+		window.Set(name, window)
+	}
+	rt.location = rt.newLocation()
+	rt.define(window, "location", func() goja.Value {
+		return rt.location
+	}, func(v goja.Value) {
+		rt.assign(v.String())
+	})
+	window.Set("document", rt.wrap(rt.root()))
+	window.Set("navigator", rt.newNavigator())
+	window.Set("history", rt.newHistory())
+	window.Set("localStorage", rt.newStorage("local"))
+	window.Set("sessionStorage", rt.newStorage("session"))
+
+	console := vm.NewObject()
+	for _, name := range []string{"log", "info", "warn", "error", "debug", "trace", "dir"} {
+		console.Set(name, func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	}
+	window.Set("console", console)
+
+	window.Set("setTimeout", func(call goja.FunctionCall) goja.Value {
+		return rt.setTimer(call, false)
+	})
+	window.Set("setInterval", func(call goja.FunctionCall) goja.Value {
+		return rt.setTimer(call, true)
+	})
+	window.Set("clearTimeout", rt.clearTimer)
+	window.Set("clearInterval", rt.clearTimer)
+
+	window.Set("addEventListener", func(call goja.FunctionCall) goja.Value {
+		rt.addListener(nil, call)
+		return goja.Undefined()
+	})
+	window.Set("removeEventListener", func(call goja.FunctionCall) goja.Value {
+		rt.removeListener(nil, call)
+		return goja.Undefined()
+	})
+	window.Set("dispatchEvent", func(call goja.FunctionCall) goja.Value {
+		return vm.ToValue(rt.dispatch(nil, call.Argument(0).ToObject(vm)))
+	})
+	window.Set("Event", rt.eventConstructor)
+	window.Set("CustomEvent", rt.eventConstructor)
+
+	// There are no dialogs, so alerts are dismissed and everything is confirmed.
+	window.Set("alert", func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	window.Set("confirm", func(goja.FunctionCall) goja.Value { return vm.ToValue(true) })
+	window.Set("prompt", func(goja.FunctionCall) goja.Value { return goja.Null() })
+	window.Set("getComputedStyle", func(call goja.FunctionCall) goja.Value {
+		return rt.style(rt.argNode(call, 0))
+	})
+
+	window.Set("btoa", func(call goja.FunctionCall) goja.Value {
+		s := call.Argument(0).String()
+		b := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xff {
+				panic(vm.NewTypeError("The string to be encoded contains characters outside of the Latin1 range."))
+			}
+			b = append(b, byte(r))
+		}
+		return vm.ToValue(base64.StdEncoding.EncodeToString(b))
+	})
+	window.Set("atob", func(call goja.FunctionCall) goja.Value {
+		s := strings.Map(func(r rune) rune {
+			if r < 0x80 && isSpace(byte(r)) {
+				return -1
+			}
+			return r
+		}, call.Argument(0).String())
+		b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			panic(vm.NewTypeError("The string to be decoded is not correctly encoded."))
+		}
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return vm.ToValue(string(r))
+	})
+}
+
+// newLocation returns the location object. Setting its properties navigates
+// to the changed URL.
+func (rt *jsRuntime) newLocation() *goja.Object {
+	vm := rt.vm
+	loc := vm.NewObject()
+	part := func(name string, get func(u *url.URL) string, set func(u *url.URL, v string)) {
+		rt.define(loc, name, func() goja.Value {
+			return vm.ToValue(get(rt.url))
+		}, func(v goja.Value) {
+			u := *rt.url
+			set(&u, v.String())
+			rt.assign(u.String())
+		})
+	}
+	rt.define(loc, "href", func() goja.Value {
+		return vm.ToValue(rt.url.String())
+	}, func(v goja.Value) {
+		rt.assign(v.String())
+	})
+	part("protocol", func(u *url.URL) string {
+		return u.Scheme + ":"
+	}, func(u *url.URL, v string) {
+		u.Scheme = strings.TrimSuffix(v, ":")
+	})
+	part("host", func(u *url.URL) string {
+		return u.Host
+	}, func(u *url.URL, v string) {
+		u.Host = v
+	})
+	part("hostname", func(u *url.URL) string {
+		return u.Hostname()
+	}, func(u *url.URL, v string) {
+		if port := u.Port(); port != "" {
+			v += ":" + port
+		}
+		u.Host = v
+	})
+	part("port", func(u *url.URL) string {
+		return u.Port()
+	}, func(u *url.URL, v string) {
+		u.Host = u.Hostname()
+		if v != "" {
+			u.Host += ":" + v
+		}
+	})
+	part("pathname", func(u *url.URL) string {
+		if p := u.EscapedPath(); p != "" {
+			return p
+		}
+		return "/"
+	}, func(u *url.URL, v string) {
+		if p, err := url.PathUnescape(v); err == nil {
+			u.Path, u.RawPath = p, ""
+		}
+	})
+	part("search", func(u *url.URL) string {
+		if u.RawQuery == "" {
+			return ""
+		}
+		return "?" + u.RawQuery
+	}, func(u *url.URL, v string) {
+		u.RawQuery = strings.TrimPrefix(v, "?")
+	})
+	part("hash", func(u *url.URL) string {
+		if u.Fragment == "" {
+			return ""
+		}
+		return "#" + u.EscapedFragment()
+	}, func(u *url.URL, v string) {
+		u.Fragment, u.RawFragment = strings.TrimPrefix(v, "#"), ""
+	})
+	rt.define(loc, "origin", func() goja.Value {
+		return vm.ToValue(rt.url.Scheme + "://" + rt.url.Host)
+	}, nil)
+
+	assign := func(call goja.FunctionCall) goja.Value {
+		rt.assign(call.Argument(0).String())
+		return goja.Undefined()
+	}
+	loc.Set("assign", assign)
+	loc.Set("replace", assign)
+	loc.Set("reload", func(goja.FunctionCall) goja.Value {
+		rt.navigate(rt.bow.reload)
+		return goja.Undefined()
+	})
+	loc.Set("toString", func(goja.FunctionCall) goja.Value {
+		return vm.ToValue(rt.url.String())
+	})
+	return loc
+}
+
+// newNavigator returns the navigator object.
+func (rt *jsRuntime) newNavigator() *goja.Object {
+	nav := rt.vm.NewObject()
+	nav.Set("userAgent", rt.bow.userAgent)
+	nav.Set("appName", "Netscape")
+	nav.Set("appVersion", strings.TrimPrefix(rt.bow.userAgent, "Mozilla/"))
+	nav.Set("platform", "")
+	nav.Set("language", "en-US")
+	nav.Set("languages", rt.vm.NewArray("en-US", "en"))
+	nav.Set("cookieEnabled", true)
+	nav.Set("onLine", true)
+	nav.Set("webdriver", false)
+	return nav
+}
+
+// newHistory returns the history object. Pages can't go back or forward, but
+// pushState and replaceState change the URL of the page.
+func (rt *jsRuntime) newHistory() *goja.Object {
+	vm := rt.vm
+	history := vm.NewObject()
+	var state goja.Value = goja.Null()
+	rt.define(history, "length", func() goja.Value {
+		return vm.ToValue(rt.bow.history.Len() + 1)
+	}, nil)
+	rt.define(history, "state", func() goja.Value {
+		return state
+	}, nil)
+	push := func(call goja.FunctionCall) goja.Value {
+		state = call.Argument(0)
+		if u := call.Argument(2); !goja.IsUndefined(u) && !goja.IsNull(u) {
+			resolved, err := rt.resolve(u.String())
+			if err != nil {
+				panic(vm.NewTypeError(err.Error()))
+			}
+			rt.url = resolved
+		}
+		return goja.Undefined()
+	}
+	history.Set("pushState", push)
+	history.Set("replaceState", push)
+	for _, name := range []string{"back", "forward", "go"} {
+		history.Set(name, func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	}
+	return history
+}
+
+// newStorage returns a localStorage or sessionStorage object. Items are kept
+// by the browser for each origin, so they last from one page to the next.
+func (rt *jsRuntime) newStorage(kind string) *goja.Object {
+	vm := rt.vm
+	if rt.bow.jsStorage == nil {
+		rt.bow.jsStorage = make(map[string]map[string]string)
+	}
+	key := kind + " " + rt.url.Scheme + "://" + rt.url.Host
+	items := rt.bow.jsStorage[key]
+	if items == nil {
+		items = make(map[string]string)
+		rt.bow.jsStorage[key] = items
+	}
+	keys := func() []string {
+		var k []string
+		for name := range items {
+			k = append(k, name)
+		}
+		sort.Strings(k)
+		return k
+	}
+
+	storage := vm.NewObject()
+	rt.define(storage, "length", func() goja.Value {
+		return vm.ToValue(len(items))
+	}, nil)
+	storage.Set("getItem", func(call goja.FunctionCall) goja.Value {
+		if v, ok := items[call.Argument(0).String()]; ok {
+			return vm.ToValue(v)
+		}
+		return goja.Null()
+	})
+	storage.Set("setItem", func(call goja.FunctionCall) goja.Value {
+		items[call.Argument(0).String()] = call.Argument(1).String()
+		return goja.Undefined()
+	})
+	storage.Set("removeItem", func(call goja.FunctionCall) goja.Value {
+		delete(items, call.Argument(0).String())
+		return goja.Undefined()
+	})
+	storage.Set("clear", func(goja.FunctionCall) goja.Value {
+		for k := range items {
+			delete(items, k)
+		}
+		return goja.Undefined()
+	})
+	storage.Set("key", func(call goja.FunctionCall) goja.Value {
+		k, i := keys(), int(call.Argument(0).ToInteger())
+		if i < 0 || i >= len(k) {
+			return goja.Null()
+		}
+		return vm.ToValue(k[i])
+	})
+	return storage
+}
+
+// cookie returns the value of document.cookie, which lists the cookies the
+// browser sends to the page. The cookie jar doesn't say which cookies are
+// HttpOnly, so they're listed too.
+func (rt *jsRuntime) cookie() string {
+	jar := rt.bow.CookieJar()
+	if jar == nil {
+		return ""
+	}
+	var pairs []string
+	for _, c := range jar.Cookies(rt.url) {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// setCookie sets a cookie from a document.cookie assignment, which uses the
+// same syntax as the Set-Cookie header.
+func (rt *jsRuntime) setCookie(v string) {
+	jar := rt.bow.CookieJar()
+	if jar == nil {
+		return
+	}
+	resp := http.Response{Header: http.Header{"Set-Cookie": {v}}}
+	var cookies []*http.Cookie
+	for _, c := range resp.Cookies() {
+		if !c.HttpOnly {
+			cookies = append(cookies, c)
+		}
+	}
+	jar.SetCookies(rt.url, cookies)
+}
+
+// runScripts runs the scripts of the page, then loads the page they
+// navigated to.
+func (bow *Browser) runScripts() error {
+	rt := newJSRuntime(bow)
+	bow.js = rt
+	rt.runPage()
+	return rt.follow()
+}
+
+// scripts returns the runtime which ran the scripts of the current page, or
+// nil when they didn't run.
+func (bow *Browser) scripts() *jsRuntime {
+	if bow.js == nil || bow.js.bow != bow || bow.js.dom != bow.state.Dom {
+		return nil
+	}
+	return bow.js
+}
+
+// RunScript runs JavaScript code in the current page, the same way as a
+// script at the end of the page, and returns the value of the code exported
+// to Go. The timers set by the code are run, and the page the code navigates
+// to is loaded, before returning. Scripts only run in pages loaded while the
+// JavaScript attribute is set.
+func (bow *Browser) RunScript(code string) (interface{}, error) {
+	defer bow.lockNavigation()()
+	rt := bow.scripts()
+	if rt == nil {
+		return nil, errors.NewPageNotLoaded("Scripts are not running in the page.")
+	}
+	stop := rt.limit()
+	v, err := rt.vm.RunScript("RunScript", code)
+	if err == nil {
+		rt.runTimers()
+	}
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if v != nil {
+		result = v.Export()
+	}
+	return result, rt.follow()
+}
+
+// ScriptErrors returns the exceptions thrown by the scripts of the current
+// page. Exceptions don't stop the page from loading, the same as in a web
+// browser, so they're only reported here.
+func (bow *Browser) ScriptErrors() []error {
+	if rt := bow.scripts(); rt != nil {
+		return rt.failures
+	}
+	return nil
+}
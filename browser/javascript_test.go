@@ -0,0 +1,222 @@
+package browser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/headzoo/surf/jar"
+	"github.com/headzoo/ut"
+)
+
+func newScriptBrowser() *Browser {
+	bow := newBrowser()
+	bow.SetCookieJar(jar.NewMemoryCookies())
+	bow.SetAttributes(AttributeMap{
+		SendReferer:     true,
+		FollowRedirects: true,
+		JavaScript:      true,
+	})
+	return bow
+}
+
+func TestJavaScript(t *testing.T) {
+	ut.Run(t)
+	var posted string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Scripts</title>
+<script src="/app.js"></script>
+<script>
+document.cookie = "session=abc; path=/";
+var list = document.getElementById("list");
+for (var i = 1; i <= 3; i++) {
+	var li = document.createElement("li");
+	li.textContent = "Item " + i;
+	li.className = "item";
+	list.appendChild(li);
+}
+document.querySelector("input[name=token]").value = btoa("secret");
+document.write("<p id='written'>Written</p>");
+</script>
+</head><body>
+<ul id="list"></ul>
+<form method="post" action="/submit"><input type="hidden" name="token"><input name="q" value="surf"></form>
+<div id="later"></div>
+<div id="api"></div>
+<script>
+setTimeout(function () {
+	document.getElementById("later").innerHTML = "<b>" + document.readyState + "</b>";
+}, 5000);
+var xhr = new XMLHttpRequest();
+xhr.open("GET", "/api?from=xhr", false);
+xhr.send();
+document.getElementById("api").setAttribute("data-xhr", JSON.parse(xhr.responseText).from);
+fetch("/api?from=fetch").then(function (r) { return r.json(); }).then(function (data) {
+	document.getElementById("api").setAttribute("data-fetch", data.from);
+});
+</script>
+</body></html>`)
+		case "/app.js":
+			w.Header().Set("Content-Type", "application/javascript")
+			fmt.Fprint(w, `document.title = "Changed by " + navigator.userAgent;`)
+		case "/api":
+			c, _ := r.Cookie("session")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"from": "%s-%s"}`, r.URL.Query().Get("from"), c.Value)
+		case "/submit":
+			body, _ := ioutil.ReadAll(r.Body)
+			posted = string(body)
+			fmt.Fprint(w, `<html><head><title>Submitted</title></head></html>`)
+		}
+	}))
+	defer ts.Close()
+
+	bow := newScriptBrowser()
+	bow.SetUserAgent("Surf")
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	ut.AssertEquals(0, len(bow.ScriptErrors()))
+	ut.AssertEquals("Changed by Surf", bow.Title())
+	ut.AssertEquals(3, bow.Find("li.item").Length())
+	ut.AssertEquals("Item 3", bow.Find("li.item").Last().Text())
+	ut.AssertEquals("Written", bow.Find("#written").Text())
+	ut.AssertEquals("complete", bow.Find("#later b").Text())
+	ut.AssertEquals("xhr-abc", bow.Find("#api").AttrOr("data-xhr", ""))
+	ut.AssertEquals("fetch-abc", bow.Find("#api").AttrOr("data-fetch", ""))
+	ut.AssertEquals("session", bow.SiteCookies()[0].Name)
+
+	v, err := bow.RunScript("document.querySelectorAll('li').length * 2")
+	ut.AssertNil(err)
+	ut.AssertEquals(int64(6), v)
+	_, err = bow.RunScript("undefinedFunction()")
+	ut.AssertNotNil(err)
+
+	f, err := bow.Form("form")
+	ut.AssertNil(err)
+	ut.AssertNil(f.Submit())
+	ut.AssertEquals("token=c2VjcmV0&q=surf", posted)
+}
+
+func TestJavaScriptDisabled(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Static</title><script>document.title = "Dynamic";</script></head></html>`)
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	ut.AssertEquals("Static", bow.Title())
+	_, err = bow.RunScript("1")
+	ut.AssertNotNil(err)
+}
+
+func TestJavaScriptNavigation(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			fmt.Fprint(w, `<html><body><script>
+setTimeout(function () { location.href = "/landing?via=" + location.pathname; }, 1000);
+</script></body></html>`)
+		case "/landing":
+			fmt.Fprintf(w, `<html><head><title>Landing %s</title></head></html>`, r.URL.Query().Get("via"))
+		case "/hash":
+			fmt.Fprint(w, `<html><head><title>Hash</title></head><body><script>
+location.hash = "#top";
+document.title = location.hash;
+</script></body></html>`)
+		case "/loop":
+			fmt.Fprint(w, `<html><body><script>location.reload();</script></body></html>`)
+		case "/errors":
+			fmt.Fprint(w, `<html><head><title>Errors</title></head><body>
+<script>throw new Error("broken");</script>
+<script>document.title = "Still running";</script>
+</body></html>`)
+		}
+	}))
+	defer ts.Close()
+
+	bow := newScriptBrowser()
+	err := bow.Open(ts.URL + "/redirect")
+	ut.AssertNil(err)
+	ut.AssertEquals("Landing /redirect", bow.Title())
+	ut.AssertEquals(ts.URL+"/redirect", bow.State().Request.Referer())
+
+	err = bow.Open(ts.URL + "/hash")
+	ut.AssertNil(err)
+	ut.AssertEquals("#top", bow.Title())
+	ut.AssertEquals(ts.URL+"/hash", bow.Url().String())
+
+	err = bow.Open(ts.URL + "/loop")
+	ut.AssertNotNil(err)
+
+	err = bow.Open(ts.URL + "/errors")
+	ut.AssertNil(err)
+	ut.AssertEquals("Still running", bow.Title())
+	ut.AssertEquals(1, len(bow.ScriptErrors()))
+}
+
+func TestJavaScriptEvents(t *testing.T) {
+	ut.Run(t)
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body>
+<a id="cancelled" href="/next" onclick="return false">Cancelled</a>
+<a id="scripted" href="#">Scripted</a>
+<span id="clicks">0</span>
+<form id="search" action="/search">
+	<input type="hidden" name="token">
+	<input type="checkbox" name="safe" value="on">
+	<button name="go" value="1">Go</button>
+</form>
+<script>
+document.getElementById("scripted").addEventListener("click", function (e) {
+	e.preventDefault();
+	var clicks = document.getElementById("clicks");
+	clicks.textContent = parseInt(clicks.textContent) + 1;
+	if (clicks.textContent === "2") {
+		window.location.assign("/next");
+	}
+});
+document.forms.search.addEventListener("submit", function () {
+	this.token.value = "t0k3n";
+	this.safe.checked = true;
+});
+</script>
+</body></html>`)
+		case "/next":
+			fmt.Fprint(w, `<html><head><title>Next</title></head></html>`)
+		case "/search":
+			query = r.URL.RawQuery
+			fmt.Fprint(w, `<html><head><title>Results</title></head></html>`)
+		}
+	}))
+	defer ts.Close()
+
+	bow := newScriptBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	ut.AssertNil(bow.Click("#cancelled"))
+	ut.AssertEquals(ts.URL, bow.Url().String())
+
+	ut.AssertNil(bow.Click("#scripted"))
+	ut.AssertEquals("1", bow.Find("#clicks").Text())
+	ut.AssertEquals(ts.URL, bow.Url().String())
+	ut.AssertNil(bow.Click("#scripted"))
+	ut.AssertEquals("Next", bow.Title())
+
+	ut.AssertTrue(bow.Back())
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	ut.AssertNil(bow.Click("button"))
+	ut.AssertEquals("Results", bow.Title())
+	ut.AssertEquals("token=t0k3n&safe=on&go=1", query)
+}
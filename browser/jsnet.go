@@ -0,0 +1,247 @@
+package browser
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// initNetwork defines XMLHttpRequest and fetch. Requests are made with the
+// client of the browser, so they share its cookies and headers, and they're
+// made without waiting for the scripts the same way timers are run.
+func (rt *jsRuntime) initNetwork() {
+	rt.vm.Set("XMLHttpRequest", rt.newXHR)
+	rt.vm.Set("fetch", rt.fetch)
+}
+
+// newXHR implements the XMLHttpRequest constructor. Asynchronous requests
+// are made from the timer queue once the running script has finished.
+func (rt *jsRuntime) newXHR(call goja.ConstructorCall) *goja.Object {
+	vm, xhr := rt.vm, call.This
+	var (
+		method    string
+		target    *url.URL
+		async     bool
+		header    http.Header
+		response  http.Header
+		listeners = make(map[string][]goja.Value)
+	)
+	for name, v := range map[string]interface{}{
+		"readyState": 0, "status": 0, "statusText": "", "responseText": "",
+		"response": "", "responseURL": "", "responseType": "", "timeout": 0,
+		"withCredentials": false,
+	} {
+		xhr.Set(name, v)
+	}
+	fire := func(typ string) {
+		evt := rt.newEvent(typ, false)
+		evt.Set("target", xhr)
+		evt.Set("currentTarget", xhr)
+		rt.call(xhr.Get("on"+typ), xhr, evt)
+		for _, l := range append([]goja.Value(nil), listeners[typ]...) {
+			rt.call(l, xhr, evt)
+		}
+	}
+
+	xhr.Set("open", func(call goja.FunctionCall) goja.Value {
+		u, err := rt.resolve(call.Argument(1).String())
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		method, target = strings.ToUpper(call.Argument(0).String()), u
+		async = len(call.Arguments) < 3 || call.Argument(2).ToBoolean()
+		header = make(http.Header)
+		xhr.Set("readyState", 1)
+		return goja.Undefined()
+	})
+	xhr.Set("setRequestHeader", func(call goja.FunctionCall) goja.Value {
+		if header == nil {
+			panic(vm.NewTypeError("The request has not been opened."))
+		}
+		header.Add(call.Argument(0).String(), call.Argument(1).String())
+		return goja.Undefined()
+	})
+	xhr.Set("getResponseHeader", func(call goja.FunctionCall) goja.Value {
+		if v, ok := response[http.CanonicalHeaderKey(call.Argument(0).String())]; ok {
+			return vm.ToValue(strings.Join(v, ", "))
+		}
+		return goja.Null()
+	})
+	xhr.Set("getAllResponseHeaders", func(goja.FunctionCall) goja.Value {
+		var lines []string
+		for k, v := range response {
+			lines = append(lines, strings.ToLower(k)+": "+strings.Join(v, ", ")+"\r\n")
+		}
+		sort.Strings(lines)
+		return vm.ToValue(strings.Join(lines, ""))
+	})
+	xhr.Set("addEventListener", func(call goja.FunctionCall) goja.Value {
+		typ := call.Argument(0).String()
+		listeners[typ] = append(listeners[typ], call.Argument(1))
+		return goja.Undefined()
+	})
+	xhr.Set("removeEventListener", func(call goja.FunctionCall) goja.Value {
+		typ, fn := call.Argument(0).String(), call.Argument(1)
+		var kept []goja.Value
+		for _, l := range listeners[typ] {
+			if !l.SameAs(fn) {
+				kept = append(kept, l)
+			}
+		}
+		listeners[typ] = kept
+		return goja.Undefined()
+	})
+	for _, name := range []string{"abort", "overrideMimeType"} {
+		xhr.Set(name, func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	}
+
+	xhr.Set("send", func(call goja.FunctionCall) goja.Value {
+		if target == nil {
+			panic(vm.NewTypeError("The request has not been opened."))
+		}
+		var body io.Reader
+		if b := call.Argument(0); !goja.IsUndefined(b) && !goja.IsNull(b) && method != "GET" && method != "HEAD" {
+			body = strings.NewReader(b.String())
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "text/plain;charset=UTF-8")
+			}
+		}
+		send := func() {
+			resp, data, err := rt.request(method, target, header, body)
+			xhr.Set("readyState", 4)
+			if err != nil {
+				fire("readystatechange")
+				fire("error")
+				fire("loadend")
+				return
+			}
+			response = resp.Header
+			xhr.Set("status", resp.StatusCode)
+			xhr.Set("statusText", statusText(resp))
+			xhr.Set("responseURL", resp.Request.URL.String())
+			xhr.Set("responseText", string(data))
+			if xhr.Get("responseType").String() == "json" {
+				v, err := rt.parseJSON(string(data))
+				if err != nil {
+					v = goja.Null()
+				}
+				xhr.Set("response", v)
+			} else {
+				xhr.Set("response", string(data))
+			}
+			fire("readystatechange")
+			fire("load")
+			fire("loadend")
+		}
+		if async {
+			rt.later(send)
+		} else {
+			send()
+		}
+		return goja.Undefined()
+	})
+	return nil
+}
+
+// fetch implements fetch. Only URLs are supported as the resource, and the
+// headers of the options must be a plain object. The promise is settled
+// before fetch returns, but the callbacks run after the script, the same as
+// all promise callbacks.
+func (rt *jsRuntime) fetch(call goja.FunctionCall) goja.Value {
+	vm := rt.vm
+	promise, resolve, reject := vm.NewPromise()
+	method, header := "GET", make(http.Header)
+	var body io.Reader
+	if opts, ok := call.Argument(1).(*goja.Object); ok {
+		if v := opts.Get("method"); v != nil && !goja.IsUndefined(v) {
+			method = strings.ToUpper(v.String())
+		}
+		if h, ok := opts.Get("headers").(*goja.Object); ok {
+			for _, k := range h.Keys() {
+				header.Set(k, h.Get(k).String())
+			}
+		}
+		if v := opts.Get("body"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+			body = strings.NewReader(v.String())
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "text/plain;charset=UTF-8")
+			}
+		}
+	}
+	u, err := rt.resolve(call.Argument(0).String())
+	if err == nil {
+		var resp *http.Response
+		var data []byte
+		if resp, data, err = rt.request(method, u, header, body); err == nil {
+			resolve(rt.newResponse(resp, data))
+		}
+	}
+	if err != nil {
+		reject(vm.NewTypeError("Failed to fetch: %s", err))
+	}
+	return vm.ToValue(promise)
+}
+
+// newResponse returns the Response object which fetch resolves to.
+func (rt *jsRuntime) newResponse(resp *http.Response, data []byte) *goja.Object {
+	vm := rt.vm
+	r := vm.NewObject()
+	r.Set("ok", resp.StatusCode >= 200 && resp.StatusCode < 300)
+	r.Set("status", resp.StatusCode)
+	r.Set("statusText", statusText(resp))
+	r.Set("url", resp.Request.URL.String())
+	r.Set("redirected", resp.Request.Response != nil)
+
+	headers := vm.NewObject()
+	headers.Set("get", func(call goja.FunctionCall) goja.Value {
+		if v, ok := resp.Header[http.CanonicalHeaderKey(call.Argument(0).String())]; ok {
+			return vm.ToValue(strings.Join(v, ", "))
+		}
+		return goja.Null()
+	})
+	headers.Set("has", func(call goja.FunctionCall) goja.Value {
+		_, ok := resp.Header[http.CanonicalHeaderKey(call.Argument(0).String())]
+		return vm.ToValue(ok)
+	})
+	r.Set("headers", headers)
+
+	r.Set("text", func(goja.FunctionCall) goja.Value {
+		p, resolve, _ := vm.NewPromise()
+		resolve(string(data))
+		return vm.ToValue(p)
+	})
+	r.Set("json", func(goja.FunctionCall) goja.Value {
+		p, resolve, reject := vm.NewPromise()
+		if v, err := rt.parseJSON(string(data)); err != nil {
+			reject(err.Value())
+		} else {
+			resolve(v)
+		}
+		return vm.ToValue(p)
+	})
+	return r
+}
+
+// parseJSON parses JSON with JSON.parse, returning the exception it throws.
+func (rt *jsRuntime) parseJSON(s string) (goja.Value, *goja.Exception) {
+	parse, _ := goja.AssertFunction(rt.vm.Get("JSON").ToObject(rt.vm).Get("parse"))
+	v, err := parse(goja.Undefined(), rt.vm.ToValue(s))
+	if err != nil {
+		if ex, ok := err.(*goja.Exception); ok {
+			return nil, ex
+		}
+		rt.fail(err)
+		return goja.Null(), nil
+	}
+	return v, nil
+}
+
+// statusText returns the reason phrase of the response status.
+func statusText(resp *http.Response) string {
+	return strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)))
+}
@@ -0,0 +1,102 @@
+package browser
+
+import (
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// Logger receives the events of a browser, such as the requests it sends and
+// the responses it receives. Events are logged with a message and key/value
+// pairs the same way as log/slog, and *slog.Logger implements the interface.
+//
+// Requests and cookies are logged at the debug level, responses, redirects and
+// form submissions at the info level, responses with an error status and
+// refused redirects at the warn level, and failed requests at the error level.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// stderrLogger is used when a browser has no logger and the
+// SURF_DEBUG_HEADERS environment variable is set.
+var stderrLogger Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+	Level: slog.LevelDebug,
+}))
+
+// discardLogger is a Logger which ignores the events.
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
+
+// SetLogger sets the logger which receives the events of the browser. Passing
+// nil removes the logger.
+func (bow *Browser) SetLogger(l Logger) {
+	bow.logger = l
+}
+
+// Logger returns the logger which receives the events of the browser. When
+// no logger has been set, the events are logged to stderr if the
+// SURF_DEBUG_HEADERS environment variable is set, and discarded otherwise.
+func (bow *Browser) Logger() Logger {
+	if bow.logger != nil {
+		return bow.logger
+	}
+	if os.Getenv("SURF_DEBUG_HEADERS") != "" {
+		return stderrLogger
+	}
+	return discardLogger{}
+}
+
+// logRequest logs the request before it's sent. The body is logged when the
+// LogBodies attribute is set and it can be read without consuming it.
+// Multipart bodies are streamed from their files, so they're never logged.
+func (bow *Browser) logRequest(req *http.Request) {
+	args := []interface{}{"method", req.Method, "url", req.URL.String(), "header", req.Header}
+	_, streamed := req.Body.(*multipartBody)
+	if bow.attributes[LogBodies] && req.GetBody != nil && !streamed {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			body.Close()
+			args = append(args, "body", string(b))
+		}
+	}
+	bow.Logger().Debug("request", args...)
+}
+
+// logResponse logs the response which loaded the current page, along with the
+// cookies set by it and the redirects which led to it.
+func (bow *Browser) logResponse(req *http.Request, resp *http.Response) {
+	l := bow.Logger()
+	for _, hop := range bow.state.Redirects {
+		for _, c := range hop.Cookies {
+			l.Debug("cookie", "url", hop.Url.String(), "name", c.Name, "domain", c.Domain, "path", c.Path)
+		}
+	}
+	for _, c := range resp.Cookies() {
+		l.Debug("cookie", "url", resp.Request.URL.String(), "name", c.Name, "domain", c.Domain, "path", c.Path)
+	}
+
+	args := []interface{}{
+		"method", req.Method,
+		"url", resp.Request.URL.String(),
+		"status", resp.StatusCode,
+		"media_type", bow.state.MediaType,
+		"size", len(bow.state.Body),
+		"duration", bow.state.Timing.Total,
+	}
+	if bow.attributes[LogBodies] {
+		args = append(args, "body", string(bow.state.Body))
+	}
+	if resp.StatusCode >= 400 {
+		l.Warn("response", args...)
+	} else {
+		l.Info("response", args...)
+	}
+}
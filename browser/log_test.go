@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/headzoo/ut"
+)
+
+type loggedEvent struct {
+	level string
+	msg   string
+	args  map[string]interface{}
+}
+
+type recordingLogger struct {
+	events []loggedEvent
+}
+
+func (l *recordingLogger) record(level, msg string, args []interface{}) {
+	e := loggedEvent{level: level, msg: msg, args: make(map[string]interface{})}
+	for i := 0; i+1 < len(args); i += 2 {
+		e.args[args[i].(string)] = args[i+1]
+	}
+	l.events = append(l.events, e)
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record("debug", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record("info", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record("warn", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record("error", msg, args) }
+
+func (l *recordingLogger) find(msg string) []loggedEvent {
+	var found []loggedEvent
+	for _, e := range l.events {
+		if e.msg == msg {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func TestLogger(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.SetCookie(w, &http.Cookie{Name: "visited", Value: "1", Path: "/"})
+			http.Redirect(w, r, "/form", http.StatusFound)
+		case "/form":
+			fmt.Fprint(w, `<html><body><form method="post" action="/submit">
+<input name="user" value="joe"><input type="password" name="pass" value="secret">
+</form></body></html>`)
+		case "/submit":
+			http.Error(w, "Denied", http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	logger := &recordingLogger{}
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{FollowRedirects: true})
+	bow.SetLogger(logger)
+	ut.AssertNil(bow.Open(ts.URL))
+
+	requests := logger.find("request")
+	ut.AssertEquals(1, len(requests))
+	ut.AssertEquals("debug", requests[0].level)
+	ut.AssertEquals(ts.URL, requests[0].args["url"])
+	redirects := logger.find("redirect")
+	ut.AssertEquals(1, len(redirects))
+	ut.AssertEquals(ts.URL+"/form", redirects[0].args["location"])
+	ut.AssertEquals(http.StatusFound, redirects[0].args["status"])
+	cookies := logger.find("cookie")
+	ut.AssertEquals(1, len(cookies))
+	ut.AssertEquals("visited", cookies[0].args["name"])
+	responses := logger.find("response")
+	ut.AssertEquals(1, len(responses))
+	ut.AssertEquals("info", responses[0].level)
+	ut.AssertEquals(ts.URL+"/form", responses[0].args["url"])
+	_, ok := responses[0].args["body"]
+	ut.AssertFalse(ok)
+
+	bow.SetAttribute(LogBodies, true)
+	f, err := bow.Form("form")
+	ut.AssertNil(err)
+	ut.AssertNil(f.Submit())
+	submits := logger.find("form submit")
+	ut.AssertEquals(1, len(submits))
+	ut.AssertEquals("POST", submits[0].args["method"])
+	ut.AssertEquals([]string{"user", "pass"}, submits[0].args["fields"])
+	requests = logger.find("request")
+	ut.AssertEquals("user=joe&pass=secret", requests[1].args["body"])
+	responses = logger.find("response")
+	ut.AssertEquals("warn", responses[1].level)
+	ut.AssertEquals("Denied\n", responses[1].args["body"])
+
+	bow.SetLogger(nil)
+	ut.AssertNil(bow.Open(ts.URL + "/form"))
+	ut.AssertEquals(2, len(logger.find("response")))
+}
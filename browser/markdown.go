@@ -0,0 +1,533 @@
+package browser
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/atom"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// Markdown converts the selection to CommonMark, using the GitHub Flavored
+// Markdown extensions for tables and strikethrough text.
+//
+// Headings, paragraphs, emphasis, links, images, lists, code blocks, block
+// quotes and tables are converted. Scripts, styles, noscript content and
+// hidden elements are skipped. Links are not resolved, see Browser.Markdown
+// to resolve them.
+func Markdown(sel *goquery.Selection) string {
+	return convertMarkdown(sel, func(u *url.URL) *url.URL { return u })
+}
+
+// Markdown converts the page to Markdown. It works just like the Markdown
+// function, except that links and images are resolved with ResolveUrl.
+func (bow *Browser) Markdown() string {
+	return convertMarkdown(bow.Find("body"), bow.ResolveUrl)
+}
+
+// convertMarkdown converts every node in the selection.
+func convertMarkdown(sel *goquery.Selection, resolve func(*url.URL) *url.URL) string {
+	c := &mdConverter{resolve: resolve}
+	var nodes []*nethtml.Node
+	for _, n := range sel.Nodes {
+		if n.Type == nethtml.DocumentNode {
+			nodes = append(nodes, childNodes(n)...)
+		} else {
+			nodes = append(nodes, n)
+		}
+	}
+	md := joinBlocks(c.blocks(nodes))
+	if md == "" {
+		return ""
+	}
+	return md + "\n"
+}
+
+// mdHardBreak marks a line break in inline content. Breaks are replaced once
+// the content of a block is complete, so breaks at the start or end of the
+// block can be removed.
+const mdHardBreak = "\x00"
+
+// mdBlock is a block of converted Markdown.
+type mdBlock struct {
+	text string
+
+	// list is true when the block is a list, which may follow the text of a
+	// list item without a blank line.
+	list bool
+}
+
+// mdConverter converts HTML nodes to Markdown.
+type mdConverter struct {
+	resolve func(*url.URL) *url.URL
+}
+
+// blocks converts the nodes to blocks. Runs of inline nodes become paragraphs.
+func (c *mdConverter) blocks(nodes []*nethtml.Node) []mdBlock {
+	var blocks []mdBlock
+	var inline strings.Builder
+	flush := func() {
+		if text := mdParagraph(inline.String()); text != "" {
+			blocks = append(blocks, mdBlock{text: text})
+		}
+		inline.Reset()
+	}
+	for _, n := range nodes {
+		if n.Type == nethtml.ElementNode && isHiddenElement(n) {
+			continue
+		}
+		if isMarkdownBlock(n) {
+			flush()
+			blocks = append(blocks, c.block(n)...)
+		} else {
+			inline.WriteString(c.inline(n))
+		}
+	}
+	flush()
+	return blocks
+}
+
+// block converts a block element.
+func (c *mdConverter) block(n *nethtml.Node) []mdBlock {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := mdLine(c.inlineChildren(n))
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []mdBlock{{text: strings.Repeat("#", level) + " " + text}}
+	case atom.P:
+		if text := mdParagraph(c.inlineChildren(n)); text != "" {
+			return []mdBlock{{text: text}}
+		}
+		return nil
+	case atom.Hr:
+		return []mdBlock{{text: "---"}}
+	case atom.Pre:
+		return []mdBlock{{text: codeBlock(n)}}
+	case atom.Blockquote:
+		text := joinBlocks(c.blocks(childNodes(n)))
+		if text == "" {
+			return nil
+		}
+		return []mdBlock{{text: prefixLines(text, "> ", ">")}}
+	case atom.Ul, atom.Ol, atom.Menu:
+		if text := c.list(n); text != "" {
+			return []mdBlock{{text: text, list: true}}
+		}
+		return nil
+	case atom.Table:
+		return c.table(n)
+	case atom.Dt:
+		if text := mdLine(c.inlineChildren(n)); text != "" {
+			return []mdBlock{{text: "**" + text + "**"}}
+		}
+		return nil
+	}
+	return c.blocks(childNodes(n))
+}
+
+// list converts the items of a ul, ol or menu element.
+func (c *mdConverter) list(n *nethtml.Node) string {
+	num := 1
+	if start, ok := nodeAttr(n, "start"); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(start)); err == nil && i >= 0 {
+			num = i
+		}
+	}
+	var items []string
+	for _, li := range childNodes(n) {
+		if li.Type != nethtml.ElementNode || li.DataAtom != atom.Li || isHiddenElement(li) {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+
+		var text string
+		for i, b := range c.blocks(childNodes(li)) {
+			switch {
+			case i == 0:
+				text = b.text
+			case b.list:
+				text += "\n" + b.text
+			default:
+				text += "\n\n" + b.text
+			}
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.TrimLeft(prefixLines(text, indent, ""), " "))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table converts a table to a GFM table. Tables without a header row use the
+// first row as the header.
+func (c *mdConverter) table(n *nethtml.Node) []mdBlock {
+	table := newTable(goquery.NewDocumentFromNode(n).Selection, c.resolve)
+	rows := append(append([][]*TableCell{}, table.HeaderRows...), table.Rows...)
+	if len(rows) == 0 {
+		return nil
+	}
+	header := len(table.HeaderRows) - 1
+	if header < 0 {
+		header = 0
+	}
+
+	var lines []string
+	for i, row := range rows[header:] {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			if !cell.Spanned && cell.Selection != nil {
+				text := mdLine(c.inlineChildren(cell.Selection.Get(0)))
+				cells[j] = strings.Replace(text, "|", `\|`, -1)
+			}
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", len(row)))
+		}
+	}
+
+	var blocks []mdBlock
+	if caption := mdLine(c.inlineChildren(firstChildElement(n, atom.Caption))); caption != "" {
+		blocks = append(blocks, mdBlock{text: caption})
+	}
+	return append(blocks, mdBlock{text: strings.Join(lines, "\n")})
+}
+
+// inline converts an inline node.
+func (c *mdConverter) inline(n *nethtml.Node) string {
+	switch n.Type {
+	case nethtml.TextNode:
+		return escapeMarkdown(collapseInline(n.Data))
+	case nethtml.ElementNode:
+	default:
+		return ""
+	}
+	if isHiddenElement(n) {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return mdHardBreak
+	case atom.Strong, atom.B:
+		return emphasize(c.inlineChildren(n), "**")
+	case atom.Em, atom.I, atom.Cite, atom.Var, atom.Dfn:
+		return emphasize(c.inlineChildren(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return emphasize(c.inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		return codeSpan(nodeText(n))
+	case atom.A:
+		return c.link(n)
+	case atom.Img:
+		return c.image(n)
+	case atom.Input:
+		if t, _ := nodeAttr(n, "type"); strings.EqualFold(t, "checkbox") {
+			if _, ok := nodeAttr(n, "checked"); ok {
+				return "[x] "
+			}
+			return "[ ] "
+		}
+		return ""
+	case atom.Select, atom.Textarea, atom.Button:
+		return ""
+	}
+	if isMarkdownBlock(n) {
+		// Block elements inside of inline content, such as a div inside of a
+		// link, are written on a line of their own.
+		return mdHardBreak + c.inlineChildren(n) + mdHardBreak
+	}
+	return c.inlineChildren(n)
+}
+
+// inlineChildren converts the children of the node as inline content.
+func (c *mdConverter) inlineChildren(n *nethtml.Node) string {
+	if n == nil {
+		return ""
+	}
+	var buf strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		buf.WriteString(c.inline(ch))
+	}
+	return buf.String()
+}
+
+// link converts an a element. Links without an href, and javascript: links,
+// are converted to their text.
+func (c *mdConverter) link(n *nethtml.Node) string {
+	text := c.inlineChildren(n)
+	href, ok := nodeAttr(n, "href")
+	href = strings.TrimSpace(href)
+	if !ok || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return text
+	}
+	href = c.resolveLink(href)
+	label := mdLine(text)
+	if label == "" {
+		label = escapeMarkdown(href)
+	} else if label == escapeMarkdown(href) && isAutolink(href) {
+		return "<" + href + ">"
+	}
+	lead, trail := outerSpace(text)
+	return lead + "[" + label + "](" + linkDestination(href) + linkTitle(n) + ")" + trail
+}
+
+// image converts an img element.
+func (c *mdConverter) image(n *nethtml.Node) string {
+	src, ok := nodeAttr(n, "src")
+	if !ok || strings.TrimSpace(src) == "" {
+		return ""
+	}
+	alt, _ := nodeAttr(n, "alt")
+	src = c.resolveLink(strings.TrimSpace(src))
+	return "![" + escapeMarkdown(collapseSpace(alt)) + "](" + linkDestination(src) + linkTitle(n) + ")"
+}
+
+// resolveLink returns the resolved URL of the href.
+func (c *mdConverter) resolveLink(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return c.resolve(u).String()
+}
+
+// isMarkdownBlock returns true for elements converted to Markdown blocks.
+func isMarkdownBlock(n *nethtml.Node) bool {
+	if n.Type != nethtml.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.P, atom.Hr,
+		atom.Pre, atom.Blockquote, atom.Ul, atom.Ol, atom.Menu, atom.Table,
+		atom.Dl, atom.Dt, atom.Dd, atom.Figure:
+		return true
+	}
+	return isBlockElement(n)
+}
+
+// codeBlock converts a pre element to a fenced code block. The language is
+// taken from a "language-" or "lang-" class on the pre element or the code
+// element inside of it.
+func codeBlock(n *nethtml.Node) string {
+	code := strings.TrimSuffix(nodeText(n), "\n")
+	lang := codeLanguage(n)
+	if lang == "" {
+		lang = codeLanguage(firstChildElement(n, atom.Code))
+	}
+	fence := strings.Repeat("`", maxRun(code, '`')+1)
+	if len(fence) < 3 {
+		fence = "```"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// codeLanguage returns the language named by the class of the element.
+func codeLanguage(n *nethtml.Node) string {
+	if n == nil {
+		return ""
+	}
+	class, _ := nodeAttr(n, "class")
+	for _, c := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(c, prefix) && len(c) > len(prefix) {
+				return c[len(prefix):]
+			}
+		}
+	}
+	return ""
+}
+
+// codeSpan wraps the text in enough backticks to contain it.
+func codeSpan(s string) string {
+	s = collapseInline(s)
+	if strings.TrimSpace(s) == "" {
+		return s
+	}
+	fence := strings.Repeat("`", maxRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// emphasize wraps the text in the delimiter. White space at the start or end
+// of the text is moved outside of the delimiters.
+func emphasize(s, delim string) string {
+	text := strings.Trim(s, " "+mdHardBreak)
+	if text == "" {
+		return s
+	}
+	lead, trail := outerSpace(s)
+	return lead + delim + text + delim + trail
+}
+
+// outerSpace returns a space for each end of the string starting or ending
+// with white space.
+func outerSpace(s string) (lead, trail string) {
+	if strings.HasPrefix(s, " ") {
+		lead = " "
+	}
+	if strings.HasSuffix(s, " ") {
+		trail = " "
+	}
+	return lead, trail
+}
+
+// linkDestination returns the URL as a link destination, which is wrapped in
+// angle brackets when it contains characters ending a destination.
+func linkDestination(href string) string {
+	if strings.ContainsAny(href, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(href) + ">"
+	}
+	return href
+}
+
+// linkTitle returns the title attribute of the element as a link title.
+func linkTitle(n *nethtml.Node) string {
+	title, ok := nodeAttr(n, "title")
+	if !ok || strings.TrimSpace(title) == "" {
+		return ""
+	}
+	title = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(collapseSpace(title))
+	return ` "` + title + `"`
+}
+
+// isAutolink returns true when the URL can be written as an autolink.
+func isAutolink(href string) bool {
+	lower := strings.ToLower(href)
+	return (strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")) &&
+		!strings.ContainsAny(href, " <>")
+}
+
+var (
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+	)
+	blockStart      = regexp.MustCompile(`^(#{1,6}|[-+=]|\d+[.)]|~~~)( |$)`)
+	mdBreakSpace    = regexp.MustCompile(` *` + mdHardBreak + ` *`)
+	mdBreakRuns     = regexp.MustCompile(`(` + mdHardBreak + `)+`)
+	mdParagraphTrim = " " + mdHardBreak
+)
+
+// escapeMarkdown escapes the characters with a meaning in inline Markdown.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// collapseInline collapses the white space in inline text with collapseSpace,
+// keeping a single space at each end of the text which had white space.
+func collapseInline(s string) string {
+	text := collapseSpace(s)
+	if text == "" {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	if r, _ := utf8.DecodeRuneInString(s); unicode.IsSpace(r) {
+		text = " " + text
+	}
+	if r, _ := utf8.DecodeLastRuneInString(s); unicode.IsSpace(r) {
+		text += " "
+	}
+	return text
+}
+
+// mdParagraph completes the inline content of a paragraph. Runs of spaces are
+// collapsed, line breaks are written, and lines which would start a block are
+// escaped.
+func mdParagraph(s string) string {
+	s = strings.Trim(mdBreakSpace.ReplaceAllString(collapseInline(s), mdHardBreak), mdParagraphTrim)
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(mdBreakRuns.ReplaceAllString(s, mdHardBreak), mdHardBreak)
+	for i, line := range lines {
+		if m := blockStart.FindStringSubmatchIndex(line); m != nil {
+			end := m[3]
+			if line[end-1] == '.' || line[end-1] == ')' {
+				line = line[:end-1] + `\` + line[end-1:]
+			} else {
+				line = `\` + line
+			}
+			lines[i] = line
+		}
+	}
+	return strings.Join(lines, "\\\n")
+}
+
+// mdLine completes inline content which must be written on a single line.
+func mdLine(s string) string {
+	return collapseSpace(strings.Replace(s, mdHardBreak, " ", -1))
+}
+
+// joinBlocks joins the blocks with blank lines.
+func joinBlocks(blocks []mdBlock) string {
+	texts := make([]string, len(blocks))
+	for i, b := range blocks {
+		texts[i] = b.text
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// prefixLines adds the prefix to each line of the text. Empty lines get the
+// empty prefix instead.
+func prefixLines(s, prefix, empty string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = empty
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maxRun returns the length of the longest run of the character in the string.
+func maxRun(s string, c rune) int {
+	max, run := 0, 0
+	for _, r := range s {
+		if r == c {
+			run++
+			if run > max {
+				max = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	return max
+}
+
+// childNodes returns the children of the node.
+func childNodes(n *nethtml.Node) []*nethtml.Node {
+	var nodes []*nethtml.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+// firstChildElement returns the first child of the node with the given tag, or nil.
+func firstChildElement(n *nethtml.Node, a atom.Atom) *nethtml.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && c.DataAtom == a {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/ut"
+)
+
+var htmlMarkdown = `<!doctype html>
+<html>
+	<head>
+		<title>Surf</title>
+		<script>var x = 1;</script>
+	</head>
+	<body>
+		<h1>Surf <em>Guide</em></h1>
+		<p>Use <strong>wax</strong> on a *new* board,<br>
+		and read the <a href="/faq" title="Questions">FAQ</a>.</p>
+		<p>1. Not a list</p>
+		<noscript><iframe src="/tracking.html"></iframe></noscript>
+		<div hidden>Hidden</div>
+		<ul>
+			<li>Board <del>old</del></li>
+			<li>Wax
+				<ol start="2"><li>Base</li><li>Top</li></ol>
+			</li>
+		</ul>
+		<blockquote><p>Go big</p><p>or go home</p></blockquote>
+		<pre><code class="language-go">fmt.Println("` + "`" + `surf` + "`" + `")
+</code></pre>
+		<p>Run <code>go test</code>, then visit <a href="https://example.com/">https://example.com/</a>.<noscript><img src="/pixel.gif"></noscript></p>
+		<img src="img/wave.png" alt="A wave">
+		<table>
+			<tr><th>Beach</th><th>Height</th></tr>
+			<tr><td><a href="north">North</a></td><td>2m | 3m</td></tr>
+		</table>
+	</body>
+</html>`
+
+var markdownExpected = "# Surf *Guide*\n\n" +
+	"Use **wax** on a \\*new\\* board,\\\nand read the [FAQ](%s/faq \"Questions\").\n\n" +
+	"1\\. Not a list\n\n" +
+	"- Board ~~old~~\n" +
+	"- Wax\n" +
+	"  2. Base\n" +
+	"  3. Top\n\n" +
+	"> Go big\n>\n> or go home\n\n" +
+	"```go\nfmt.Println(\"`surf`\")\n```\n\n" +
+	"Run `go test`, then visit <https://example.com/>.\n\n" +
+	"![A wave](%s/guide/img/wave.png)\n\n" +
+	"| Beach | Height |\n| --- | --- |\n| [North](%s/guide/north) | 2m \\| 3m |\n"
+
+func TestMarkdown(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlMarkdown))
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL + "/guide/")
+	ut.AssertNil(err)
+	expected := strings.Replace(markdownExpected, "%s", ts.URL, -1)
+	ut.AssertEquals(expected, bow.Markdown())
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlMarkdown))
+	ut.AssertNil(err)
+	ut.AssertEquals("# Surf *Guide*\n", Markdown(doc.Find("h1")))
+	ut.AssertEquals("> Go big\n>\n> or go home\n", Markdown(doc.Find("blockquote")))
+	ut.AssertEquals("[North](north)\n", Markdown(doc.Find("td a")))
+}
@@ -0,0 +1,167 @@
+package browser
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/headzoo/surf/jar"
+)
+
+// Metrics receives the measurements of the pages loaded by a browser, which
+// are tagged with the "host", "method" and "status" of the request.
+//
+// Each page adds one to the "surf.requests" counter, and records the
+// "surf.request.duration", "surf.request.dns", "surf.request.connect",
+// "surf.request.tls", "surf.request.ttfb" and "surf.request.download"
+// histograms in seconds, and the "surf.response.size" and
+// "surf.response.compressed_size" histograms in bytes. Requests which fail
+// without a response add one to the "surf.request.errors" counter, which is
+// only tagged with the host and method.
+type Metrics interface {
+	// Count adds the value to the named counter.
+	Count(name string, value int64, tags map[string]string)
+
+	// Observe records the value in the named histogram.
+	Observe(name string, value float64, tags map[string]string)
+}
+
+// SetMetrics sets the metrics sink which receives the measurements of the
+// pages loaded by the browser. Passing nil removes the sink.
+func (bow *Browser) SetMetrics(m Metrics) {
+	bow.metrics = m
+}
+
+// recordMetrics sends the measurements of the page to the metrics sink.
+func (bow *Browser) recordMetrics(resp *http.Response, t jar.Timing) {
+	if bow.metrics == nil {
+		return
+	}
+	tags := map[string]string{
+		"host":   resp.Request.URL.Host,
+		"method": resp.Request.Method,
+		"status": strconv.Itoa(resp.StatusCode),
+	}
+	bow.metrics.Count("surf.requests", 1, tags)
+	for name, d := range map[string]time.Duration{
+		"surf.request.duration": t.Total,
+		"surf.request.dns":      t.DNS,
+		"surf.request.connect":  t.Connect,
+		"surf.request.tls":      t.TLS,
+		"surf.request.ttfb":     t.TTFB,
+		"surf.request.download": t.Download,
+	} {
+		bow.metrics.Observe(name, d.Seconds(), tags)
+	}
+	bow.metrics.Observe("surf.response.size", float64(t.Size), tags)
+	bow.metrics.Observe("surf.response.compressed_size", float64(t.CompressedSize), tags)
+}
+
+// recordError counts a request which failed without a response.
+func (bow *Browser) recordError(req *http.Request) {
+	if bow.metrics != nil {
+		bow.metrics.Count("surf.request.errors", 1, map[string]string{
+			"host":   req.URL.Host,
+			"method": req.Method,
+		})
+	}
+}
+
+// requestTimer times the phases of a request with httptrace. The hooks may
+// be called from the goroutines which dial connections, so the times are
+// guarded by a mutex.
+type requestTimer struct {
+	mu        sync.Mutex
+	timing    jar.Timing
+	dnsStart  time.Time
+	connStart time.Time
+	tlsStart  time.Time
+	firstByte time.Time
+}
+
+// newRequestTimer returns a *requestTimer which starts timing now.
+func newRequestTimer() *requestTimer {
+	return &requestTimer{timing: jar.Timing{Start: time.Now()}}
+}
+
+// trace returns the hooks which time the request.
+func (t *requestTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.timing.DNS += time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			t.connStart = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			if err == nil {
+				t.timing.Connect += time.Since(t.connStart)
+			}
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.timing.TLS += time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timing.Reused = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.mu.Unlock()
+		},
+	}
+}
+
+// done returns the timing of the request once its body has been read, with
+// the size of the body before and after it was decoded.
+func (t *requestTimer) done(compressedSize, size int64) jar.Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	timing := t.timing
+	timing.Total = now.Sub(timing.Start)
+	if !t.firstByte.IsZero() {
+		timing.TTFB = t.firstByte.Sub(timing.Start)
+		timing.Download = now.Sub(t.firstByte)
+	}
+	timing.CompressedSize = compressedSize
+	timing.Size = size
+	return timing
+}
+
+// countingReader counts the bytes read from a response body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read reads from the body and counts the bytes.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,83 @@
+package browser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/headzoo/ut"
+)
+
+type recordingMetrics struct {
+	counts       map[string]int64
+	observations map[string][]float64
+	tags         []map[string]string
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		counts:       make(map[string]int64),
+		observations: make(map[string][]float64),
+	}
+}
+
+func (m *recordingMetrics) Count(name string, value int64, tags map[string]string) {
+	m.counts[name] += value
+	m.tags = append(m.tags, tags)
+}
+
+func (m *recordingMetrics) Observe(name string, value float64, tags map[string]string) {
+	m.observations[name] = append(m.observations[name], value)
+}
+
+func TestMetrics(t *testing.T) {
+	ut.Run(t)
+	page := "<html><head><title>Compressed</title></head><body>" + strings.Repeat("<p>Surf</p>", 500) + "</body></html>"
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(page))
+	w.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(gz.Bytes())
+	}))
+
+	metrics := newRecordingMetrics()
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{FollowRedirects: true})
+	bow.SetMetrics(metrics)
+	ut.AssertNil(bow.Open(ts.URL))
+	ut.AssertEquals("Compressed", bow.Title())
+
+	timing := bow.State().Timing
+	ut.AssertEquals(int64(len(page)), timing.Size)
+	ut.AssertEquals(int64(gz.Len()), timing.CompressedSize)
+	ut.AssertTrue(timing.Connect > 0)
+	ut.AssertTrue(timing.TTFB > 0)
+	ut.AssertTrue(timing.Total >= timing.TTFB+timing.Download)
+	ut.AssertFalse(timing.Start.IsZero())
+
+	ut.AssertEquals(int64(1), metrics.counts["surf.requests"])
+	ut.AssertEquals(strings.TrimPrefix(ts.URL, "http://"), metrics.tags[0]["host"])
+	ut.AssertEquals("200", metrics.tags[0]["status"])
+	ut.AssertEquals([]float64{float64(len(page))}, metrics.observations["surf.response.size"])
+	ut.AssertEquals(1, len(metrics.observations["surf.request.ttfb"]))
+
+	ut.AssertNil(bow.Open(ts.URL + "/missing"))
+	ut.AssertTrue(bow.State().Timing.Reused)
+	ut.AssertEquals(int64(2), metrics.counts["surf.requests"])
+	ut.AssertEquals("404", metrics.tags[1]["status"])
+
+	ts.Close()
+	ut.AssertNotNil(bow.Open(ts.URL))
+	ut.AssertEquals(int64(1), metrics.counts["surf.request.errors"])
+}
@@ -0,0 +1,289 @@
+package browser
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"os"
+	"sort"
+
+	"github.com/headzoo/surf/errors"
+)
+
+// UploadProgressFunc is called while a request body is being sent. Sent is the
+// number of bytes sent so far, and total is the size of the body, or -1 when
+// the size is not known in advance.
+type UploadProgressFunc func(sent, total int64)
+
+// multipartBody streams a multipart/form-data body.
+//
+// The body is made up of the boundaries and headers written by a
+// multipart.Writer, with the contents of each file read in between them.
+// Files are only opened while they are being read, so large files are never
+// held in memory.
+//
+// The body can be sent again, such as when a 307 redirect is followed, unless
+// the data of a file can't be read again, which is when it isn't an io.Seeker.
+type multipartBody struct {
+	chunks   []multipartChunk
+	parts    []io.Reader
+	size     int64
+	sent     int64
+	replay   bool
+	progress UploadProgressFunc
+}
+
+// multipartChunk is a piece of a multipart body, which is either the
+// boundaries and headers written by the multipart.Writer, or a file.
+type multipartChunk struct {
+	data   []byte
+	name   string
+	file   *File
+	size   int64
+	rewind func() error
+}
+
+// multipartPart is a field or a file of a multipart body. A file part without
+// a file is sent the way browsers send an input type file without a file.
+type multipartPart struct {
+	name   string
+	value  string
+	file   *File
+	isFile bool
+}
+
+// multipartParts returns the parts for the given fields in the order given,
+// followed by the files sorted by field name.
+func multipartParts(fields FormValues, files MultiFileSet) []multipartPart {
+	parts := make([]multipartPart, 0, len(fields)+len(files))
+	for _, f := range fields {
+		parts = append(parts, multipartPart{name: f.Name, value: f.Value})
+	}
+	for _, k := range fileNames(files) {
+		parts = append(parts, fileParts(k, files[k])...)
+	}
+	return parts
+}
+
+// fileParts returns a part for each of the files with the given name, or a
+// single empty file part when there are no files.
+func fileParts(name string, files []*File) []multipartPart {
+	if len(files) == 0 {
+		return []multipartPart{{name: name, isFile: true}}
+	}
+	parts := make([]multipartPart, 0, len(files))
+	for _, file := range files {
+		parts = append(parts, multipartPart{name: name, file: file, isFile: true})
+	}
+	return parts
+}
+
+// fileNames returns the names in the file set sorted.
+func fileNames(files MultiFileSet) []string {
+	names := make([]string, 0, len(files))
+	for k := range files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newMultipartBody creates a multipart body for the given parts, and returns
+// it with the content type of the body. The parts are written in the order
+// given.
+func newMultipartBody(parts []multipartPart) (*multipartBody, string, error) {
+	buff := &bytes.Buffer{}
+	writer := multipart.NewWriter(buff)
+	body := &multipartBody{replay: true}
+	known := true
+
+	flush := func() {
+		if buff.Len() > 0 {
+			b := make([]byte, buff.Len())
+			copy(b, buff.Bytes())
+			body.chunks = append(body.chunks, multipartChunk{data: b})
+			body.size += int64(len(b))
+			buff.Reset()
+		}
+	}
+
+	for _, p := range parts {
+		if !p.isFile {
+			if err := writer.WriteField(p.name, p.value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		if err := writeFileHeader(writer, p.name, p.file); err != nil {
+			return nil, "", err
+		}
+		if p.file == nil {
+			continue
+		}
+		flush()
+		n := p.file.Size()
+		rewind, ok := p.file.rewinder()
+		if !ok {
+			body.replay = false
+		}
+		body.chunks = append(body.chunks, multipartChunk{name: p.name, file: p.file, size: n, rewind: rewind})
+		if n >= 0 {
+			body.size += n
+		} else {
+			known = false
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	flush()
+	if !known {
+		body.size = -1
+	}
+	body.parts = body.readers()
+
+	return body, writer.FormDataContentType(), nil
+}
+
+// readers returns the readers of the chunks of the body, which read it from
+// the start.
+func (mb *multipartBody) readers() []io.Reader {
+	parts := make([]io.Reader, len(mb.chunks))
+	for i, c := range mb.chunks {
+		if c.file == nil {
+			parts[i] = bytes.NewReader(c.data)
+		} else {
+			parts[i] = &lazyFile{name: c.name, file: c.file, size: c.size, rewind: c.rewind}
+		}
+	}
+	return parts
+}
+
+// getBody returns the function which returns a copy of the body to send it
+// again, which is used as the GetBody of the request. It returns nil when the
+// body can't be sent again.
+func (mb *multipartBody) getBody() func() (io.ReadCloser, error) {
+	if !mb.replay {
+		return nil
+	}
+	return func() (io.ReadCloser, error) {
+		return &multipartBody{
+			chunks:   mb.chunks,
+			parts:    mb.readers(),
+			size:     mb.size,
+			replay:   true,
+			progress: mb.progress,
+		}, nil
+	}
+}
+
+// Read reads the next bytes of the body.
+func (mb *multipartBody) Read(p []byte) (int, error) {
+	for len(mb.parts) > 0 {
+		n, err := mb.parts[0].Read(p)
+		if n > 0 {
+			mb.sent += int64(n)
+			if mb.progress != nil {
+				mb.progress(mb.sent, mb.size)
+			}
+		}
+		if err == io.EOF {
+			mb.parts = mb.parts[1:]
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+	return 0, io.EOF
+}
+
+// Close closes any file which is still open.
+func (mb *multipartBody) Close() error {
+	var err error
+	for _, p := range mb.parts {
+		if lf, ok := p.(*lazyFile); ok {
+			if cerr := lf.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	mb.parts = nil
+	return err
+}
+
+// Size returns the number of bytes in the body, or -1 when it's not known.
+func (mb *multipartBody) Size() int64 {
+	return mb.size
+}
+
+// lazyFile reads the contents of a *File, opening it on the first read and
+// closing it once all of the data has been read.
+//
+// The size is the number of bytes counted in the Content-Length of the body,
+// or -1 when it's not known. Reading fails when the file doesn't have that
+// many bytes, which happens when it changes after the body was created.
+//
+// Rewind moves the data back to where it was when the body was created,
+// before it's opened, so each copy of the body reads all of it.
+type lazyFile struct {
+	name   string
+	file   *File
+	size   int64
+	read   int64
+	data   io.Reader
+	closer func() error
+	rewind func() error
+}
+
+// Read reads the next bytes of the file.
+func (lf *lazyFile) Read(p []byte) (int, error) {
+	if lf.data == nil {
+		if lf.rewind != nil {
+			if err := lf.rewind(); err != nil {
+				return 0, err
+			}
+		}
+		data, closer, err := lf.file.open()
+		if err != nil {
+			return 0, err
+		}
+		lf.data, lf.closer = data, closer
+		if fh, ok := data.(*os.File); ok && lf.size >= 0 {
+			info, err := fh.Stat()
+			if err == nil && info.Size() != lf.size {
+				return 0, lf.sizeError(info.Size())
+			}
+		}
+	}
+	n, err := lf.data.Read(p)
+	lf.read += int64(n)
+	if lf.size >= 0 && (lf.read > lf.size || (err == io.EOF && lf.read != lf.size)) {
+		return n, lf.sizeError(lf.read)
+	}
+	if err == io.EOF {
+		if cerr := lf.Close(); cerr != nil {
+			return n, cerr
+		}
+	}
+	return n, err
+}
+
+// sizeError returns the error for a file which no longer has the size sent
+// in the Content-Length of the body.
+func (lf *lazyFile) sizeError(size int64) error {
+	return errors.NewInvalidFormValue(
+		"The file '%s' changed from %d to %d bytes before it was sent.",
+		lf.file.fileName, lf.size, size).With(errors.Fields{Field: lf.name})
+}
+
+// Close closes the file when it's open.
+func (lf *lazyFile) Close() error {
+	if lf.closer == nil {
+		return nil
+	}
+	closer := lf.closer
+	lf.closer = nil
+	return closer()
+}
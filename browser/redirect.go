@@ -0,0 +1,94 @@
+package browser
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/headzoo/surf/errors"
+	"github.com/headzoo/surf/jar"
+)
+
+// RedirectFunc is called before a redirect is followed with the response
+// which redirected the request. Returning an error stops the redirect, and
+// the error is returned by the method which made the request.
+type RedirectFunc func(hop *jar.Redirect) error
+
+// RedirectPolicy controls which redirects are followed by browsers with the
+// FollowRedirects attribute set. The zero value follows every redirect.
+type RedirectPolicy struct {
+	// MaxHops is the most redirects followed for a request. Zero means there
+	// is no limit.
+	MaxHops int
+
+	// SameHost stops redirects to another host.
+	SameHost bool
+
+	// NoDowngrade stops redirects from HTTPS to HTTP.
+	NoDowngrade bool
+
+	// Approve is called for each redirect which the other rules allow.
+	Approve RedirectFunc
+}
+
+// RedirectError is returned when a redirect isn't followed, because the
+// FollowRedirects attribute isn't set or the RedirectPolicy refused it.
+type RedirectError struct {
+	// Redirects holds the redirects which were followed, oldest first, with
+	// the refused redirect last.
+	Redirects []*jar.Redirect
+
+	// Err is the error which stopped the redirect.
+	Err error
+}
+
+// Error returns the message of the error which stopped the redirect.
+func (e *RedirectError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error which stopped the redirect.
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
+
+// newRedirectError returns a *RedirectError for the error returned by an
+// http.Client along with resp, which is the response of the refused redirect.
+func newRedirectError(resp *http.Response, err error) *RedirectError {
+	return &RedirectError{
+		Redirects: append(redirectChain(resp), jar.NewRedirect(resp)),
+		Err:       err,
+	}
+}
+
+// check returns an error when the policy doesn't allow the redirect to req.
+// Via holds the requests which have been made, oldest first.
+func (p RedirectPolicy) check(req *http.Request, via []*http.Request) error {
+	prev := via[len(via)-1].URL
+	fields := errors.Fields{URL: req.URL.String()}
+	if p.MaxHops > 0 && len(via) > p.MaxHops {
+		return errors.NewLocation(
+			"Stopped after %d redirects. Cannot follow '%s'.", p.MaxHops, req.URL.String()).With(fields)
+	}
+	if p.SameHost && !strings.EqualFold(prev.Hostname(), req.URL.Hostname()) {
+		return errors.NewLocation(
+			"Redirects to other hosts are disabled. Cannot follow '%s'.", req.URL.String()).With(fields)
+	}
+	if p.NoDowngrade && prev.Scheme == "https" && req.URL.Scheme == "http" {
+		return errors.NewLocation(
+			"Redirects from HTTPS to HTTP are disabled. Cannot follow '%s'.", req.URL.String()).With(fields)
+	}
+	if p.Approve != nil && req.Response != nil {
+		return p.Approve(jar.NewRedirect(req.Response))
+	}
+	return nil
+}
+
+// redirectChain returns the redirects which were followed to get the
+// response, oldest first.
+func redirectChain(resp *http.Response) []*jar.Redirect {
+	var chain []*jar.Redirect
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		chain = append([]*jar.Redirect{jar.NewRedirect(r)}, chain...)
+	}
+	return chain
+}
@@ -0,0 +1,138 @@
+package browser
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/headzoo/surf/errors"
+	"github.com/headzoo/surf/jar"
+	"github.com/headzoo/ut"
+)
+
+func TestRedirectChain(t *testing.T) {
+	ut.Run(t)
+	var other string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sso", Value: "1"})
+			http.Redirect(w, r, "/callback", http.StatusMovedPermanently)
+		case "/callback":
+			http.Redirect(w, r, "/home", http.StatusFound)
+		case "/home":
+			fmt.Fprint(w, `<html><head><title>Home</title></head></html>`)
+		case "/away":
+			http.Redirect(w, r, other, http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+	other = strings.Replace(ts.URL, "127.0.0.1", "localhost", 1) + "/home"
+
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{FollowRedirects: true})
+	err := bow.Open(ts.URL + "/login")
+	ut.AssertNil(err)
+	ut.AssertEquals("Home", bow.Title())
+	redirects := bow.State().Redirects
+	ut.AssertEquals(2, len(redirects))
+	ut.AssertEquals(ts.URL+"/login", redirects[0].Url.String())
+	ut.AssertEquals("GET", redirects[0].Method)
+	ut.AssertEquals(http.StatusMovedPermanently, redirects[0].StatusCode)
+	ut.AssertEquals(ts.URL+"/callback", redirects[0].Location.String())
+	ut.AssertEquals("sso", redirects[0].Cookies[0].Name)
+	ut.AssertEquals(http.StatusFound, redirects[1].StatusCode)
+	ut.AssertEquals(ts.URL+"/home", redirects[1].Location.String())
+
+	err = bow.Open(ts.URL + "/home")
+	ut.AssertNil(err)
+	ut.AssertEquals(0, len(bow.State().Redirects))
+
+	bow.SetRedirectPolicy(RedirectPolicy{MaxHops: 1})
+	err = bow.Open(ts.URL + "/login")
+	ut.AssertNotNil(err)
+	var rerr *RedirectError
+	ut.AssertTrue(stderrors.As(err, &rerr))
+	ut.AssertEquals(2, len(rerr.Redirects))
+	ut.AssertEquals(ts.URL+"/login", rerr.Redirects[0].Url.String())
+	ut.AssertEquals(ts.URL+"/home", rerr.Redirects[1].Location.String())
+	ut.AssertTrue(stderrors.Is(err, errors.Location{}))
+
+	bow.SetRedirectPolicy(RedirectPolicy{SameHost: true})
+	err = bow.Open(ts.URL + "/login")
+	ut.AssertNil(err)
+	err = bow.Open(ts.URL + "/away")
+	ut.AssertNotNil(err)
+
+	var hops []string
+	bow.SetRedirectPolicy(RedirectPolicy{
+		Approve: func(hop *jar.Redirect) error {
+			hops = append(hops, hop.Location.Path)
+			if hop.Location.Path == "/home" {
+				return fmt.Errorf("denied")
+			}
+			return nil
+		},
+	})
+	err = bow.Open(ts.URL + "/login")
+	ut.AssertNotNil(err)
+	ut.AssertEquals([]string{"/callback", "/home"}, hops)
+}
+
+func TestRedirectDowngrade(t *testing.T) {
+	ut.Run(t)
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Plain</title></head></html>`)
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plain.URL, http.StatusFound)
+	}))
+	defer secure.Close()
+
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{FollowRedirects: true})
+	bow.SetTransport(secure.Client().Transport)
+	err := bow.Open(secure.URL)
+	ut.AssertNil(err)
+	ut.AssertEquals("Plain", bow.Title())
+
+	bow.SetRedirectPolicy(RedirectPolicy{NoDowngrade: true})
+	err = bow.Open(secure.URL)
+	ut.AssertNotNil(err)
+}
+
+func TestAssetRedirect(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><img src="/cdn/pixel.gif"></body></html>`)
+		case "/cdn/pixel.gif":
+			http.Redirect(w, r, "/pixel.gif", http.StatusFound)
+		case "/pixel.gif":
+			fmt.Fprint(w, "pixel")
+		}
+	}))
+	defer ts.Close()
+
+	// Assets follow redirects without the attributes and policy of the browser.
+	approved := 0
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{FollowRedirects: false})
+	bow.SetRedirectPolicy(RedirectPolicy{Approve: func(hop *jar.Redirect) error {
+		approved++
+		return nil
+	}})
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+	var out bytes.Buffer
+	_, err = bow.Images()[0].Download(&out)
+	ut.AssertNil(err)
+	ut.AssertEquals("pixel", out.String())
+	ut.AssertEquals(0, approved)
+}
@@ -0,0 +1,288 @@
+package browser
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/headzoo/surf/errors"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// RefreshLimit is the most refreshes followed in a row, which stops pages
+// which refresh to each other from loading forever.
+var RefreshLimit = 10
+
+// MaxRefreshDelay is the longest refresh delay followed by browsers with the
+// SynchronousRefresh attribute set. Longer refreshes are usually pages which
+// reload themselves to update, rather than redirects, and are ignored.
+var MaxRefreshDelay = 10 * time.Second
+
+// refreshState holds the refresh which is waiting to be followed in the
+// background, or which is being followed, and the last error from following
+// a refresh, which hasn't been returned by WaitRefresh yet.
+//
+// Nav is the navigation lock, which is held while the browser loads a page,
+// so a refresh followed in the background and a page loaded by the caller
+// are never loaded at the same time.
+type refreshState struct {
+	mu      sync.Mutex
+	nav     sync.Mutex
+	pending *pendingRefresh
+	err     error
+}
+
+// pendingRefresh is a refresh followed in the background once its timer fires.
+// Fired is set once the refresh holds the navigation lock, after which it
+// can't be cancelled. Done is closed once the refresh has been followed, or
+// has been cancelled.
+type pendingRefresh struct {
+	timer *time.Timer
+	fired bool
+	done  chan struct{}
+}
+
+// handleRefresh follows the refresh of the page, which is set by the Refresh
+// header or a refresh meta tag.
+//
+// Refreshes are followed in the background once their delay has passed,
+// unless the SynchronousRefresh attribute is set, in which case they're
+// followed straight away, without waiting for the delay, before the request
+// which loaded the page returns.
+func (bow *Browser) handleRefresh() error {
+	if !bow.attributes[MetaRefreshHandling] {
+		return nil
+	}
+	delay, target, ok := bow.pageRefresh()
+	if !ok {
+		return nil
+	}
+	depth := bow.refreshDepth + 1
+	if bow.attributes[SynchronousRefresh] {
+		if delay > MaxRefreshDelay {
+			return nil
+		}
+		return bow.followRefresh(depth, target)
+	}
+	if bow.refresh == nil {
+		bow.refresh = &refreshState{}
+	}
+	rs := bow.refresh
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	r := &pendingRefresh{done: make(chan struct{})}
+	rs.pending = r
+	r.timer = time.AfterFunc(delay, func() {
+		bow.runRefresh(r, depth, target)
+	})
+	return nil
+}
+
+// runRefresh follows the refresh r when its timer fires, unless it has been
+// cancelled by loading another page while it waited for the navigation lock.
+func (bow *Browser) runRefresh(r *pendingRefresh, depth int, target *url.URL) {
+	rs := bow.refresh
+	rs.nav.Lock()
+	defer rs.nav.Unlock()
+	rs.mu.Lock()
+	if rs.pending != r {
+		rs.mu.Unlock()
+		return
+	}
+	r.fired = true
+	rs.mu.Unlock()
+
+	err := bow.followRefresh(depth, target)
+
+	rs.mu.Lock()
+	if err != nil {
+		rs.err = err
+	}
+	if rs.pending == r {
+		rs.pending = nil
+	}
+	close(r.done)
+	rs.mu.Unlock()
+}
+
+// cancelRefresh stops the refresh which is waiting to be followed. It's
+// called with the navigation lock held, so the only refresh which may be
+// being followed is the one loading the page, which is left to finish.
+func (bow *Browser) cancelRefresh() {
+	rs := bow.refresh
+	if rs == nil {
+		return
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if r := rs.pending; r != nil && !r.fired {
+		r.timer.Stop()
+		rs.pending = nil
+		close(r.done)
+	}
+}
+
+// WaitRefresh waits for the refreshes of the page to be followed in the
+// background, including the refreshes of the pages they load, until a page
+// which doesn't refresh is loaded. It returns the error from following them,
+// or from an earlier refresh which failed since WaitRefresh was last called.
+// It returns straight away when the page doesn't refresh, or the refresh was
+// followed when the page loaded because the SynchronousRefresh attribute is
+// set.
+//
+// Refreshes change the page from another goroutine, so call WaitRefresh
+// before reading the page when it may be refreshing. The methods which load
+// pages wait for a refresh which is being followed to finish, and cancel the
+// ones which are waiting.
+func (bow *Browser) WaitRefresh() error {
+	rs := bow.refresh
+	if rs == nil {
+		return nil
+	}
+	for {
+		rs.mu.Lock()
+		r := rs.pending
+		rs.mu.Unlock()
+		if r == nil {
+			break
+		}
+		<-r.done
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	err := rs.err
+	rs.err = nil
+	return err
+}
+
+// lockNavigation holds the navigation lock until the returned function is
+// called. It's held by the exported methods which load pages, and the pages
+// they load in turn, such as refreshes and the pages scripts navigate to,
+// are loaded without taking it again.
+func (bow *Browser) lockNavigation() func() {
+	if bow.refresh == nil {
+		bow.refresh = &refreshState{}
+	}
+	rs := bow.refresh
+	rs.nav.Lock()
+	return rs.nav.Unlock
+}
+
+// followRefresh loads the refresh target, or reloads the page when the target
+// is nil. The depth is the number of refreshes followed in a row.
+func (bow *Browser) followRefresh(depth int, target *url.URL) error {
+	if depth > RefreshLimit {
+		return errors.New("Pages refreshed more than %d times in a row.", RefreshLimit)
+	}
+	bow.refreshDepth = depth
+	defer func() { bow.refreshDepth = 0 }()
+	if target == nil {
+		return bow.reload()
+	}
+	return bow.httpGET(target, bow.Url())
+}
+
+// pageRefresh returns the delay and target of the refresh of the page. The
+// Refresh header is used before refresh meta tags, and the target is nil
+// when the page refreshes itself.
+func (bow *Browser) pageRefresh() (time.Duration, *url.URL, bool) {
+	var contents []string
+	if bow.state.Response != nil {
+		contents = append(contents, bow.state.Response.Header.Get("Refresh"))
+	}
+	if isHTMLMediaType(bow.MediaType()) {
+		for _, n := range findElements(bow.state.Dom.Get(0), func(n *nethtml.Node) bool {
+			equiv, _ := nodeAttr(n, "http-equiv")
+			return n.DataAtom == atom.Meta && strings.EqualFold(strings.TrimSpace(equiv), "refresh")
+		}) {
+			if content, ok := nodeAttr(n, "content"); ok {
+				contents = append(contents, content)
+			}
+		}
+	}
+	for _, content := range contents {
+		delay, target, ok := parseRefresh(content)
+		if !ok {
+			continue
+		}
+		if target == "" {
+			return delay, nil, true
+		}
+		u, err := url.Parse(target)
+		if err != nil {
+			continue
+		}
+		return delay, bow.ResolveUrl(u), true
+	}
+	return 0, nil, false
+}
+
+// parseRefresh parses the content of a refresh meta tag, or the Refresh
+// header, such as "5; url=/next". It returns the delay, the target URL, which
+// is empty when the page refreshes itself, and false when the content isn't
+// valid.
+//
+// The content is parsed the way web browsers parse it, so fractions of
+// seconds are ignored, the "url=" prefix is optional, and the URL may be
+// quoted.
+func parseRefresh(content string) (time.Duration, string, bool) {
+	s := trimSpaceLeft(content)
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i == 0 && (s == "" || s[0] != '.') {
+		return 0, "", false
+	}
+	var secs uint64
+	if i > 0 {
+		var err error
+		if secs, err = strconv.ParseUint(s[:i], 10, 32); err != nil {
+			return 0, "", false
+		}
+	}
+	delay := time.Duration(secs) * time.Second
+	for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+		i++
+	}
+	s = s[i:]
+	if s == "" {
+		return delay, "", true
+	}
+	if s[0] != ';' && s[0] != ',' && !isSpace(s[0]) {
+		return 0, "", false
+	}
+	s = trimSpaceLeft(s)
+	if s != "" && (s[0] == ';' || s[0] == ',') {
+		s = trimSpaceLeft(s[1:])
+	}
+
+	target := s
+	if len(s) >= 3 && strings.EqualFold(s[:3], "url") {
+		if rest := trimSpaceLeft(s[3:]); rest != "" && rest[0] == '=' {
+			target = trimSpaceLeft(rest[1:])
+		}
+	}
+	if target != "" && (target[0] == '"' || target[0] == '\'') {
+		quote := target[0]
+		target = target[1:]
+		if j := strings.IndexByte(target, quote); j >= 0 {
+			target = target[:j]
+		}
+	}
+	return delay, strings.TrimRight(target, " \t\n\r\f"), true
+}
+
+// trimSpaceLeft removes the leading HTML white space from s.
+func trimSpaceLeft(s string) string {
+	return strings.TrimLeft(s, " \t\n\r\f")
+}
+
+// isDigit returns true for the ASCII digits.
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
@@ -0,0 +1,166 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/headzoo/ut"
+)
+
+func TestParseRefresh(t *testing.T) {
+	ut.Run(t)
+	tests := []struct {
+		content string
+		delay   time.Duration
+		target  string
+		ok      bool
+	}{
+		{"5", 5 * time.Second, "", true},
+		{"5; url=/next", 5 * time.Second, "/next", true},
+		{" 0 ;URL = 'http://example.com/a b' ", 0, "http://example.com/a b", true},
+		{"1.5, /next", time.Second, "/next", true},
+		{"3 \"/quoted\"", 3 * time.Second, "/quoted", true},
+		{"0;url=/next?a=1;b=2", 0, "/next?a=1;b=2", true},
+		{"", 0, "", false},
+		{"soon", 0, "", false},
+		{"5x; url=/next", 0, "", false},
+	}
+	for _, test := range tests {
+		delay, target, ok := parseRefresh(test.content)
+		ut.AssertEquals(test.ok, ok)
+		ut.AssertEquals(test.delay, delay)
+		ut.AssertEquals(test.target, target)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ut.Run(t)
+	refreshed := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/meta":
+			fmt.Fprint(w, `<html><head><title>Meta</title><meta http-equiv="Refresh" content="0; url=/header"></head></html>`)
+		case "/header":
+			w.Header().Set("Refresh", "1;url=/landing")
+			fmt.Fprint(w, `<html><head><title>Header</title></head></html>`)
+		case "/landing":
+			fmt.Fprint(w, `<html><head><title>Landing</title></head></html>`)
+		case "/auto":
+			fmt.Fprint(w, `<html><head><title>Auto</title><meta http-equiv="refresh" content="0; url=/done"></head></html>`)
+		case "/done":
+			fmt.Fprint(w, `<html><head><title>Done</title></head></html>`)
+			refreshed <- r.URL.Path
+		case "/slow":
+			fmt.Fprint(w, `<html><head><title>Slow</title><meta http-equiv="refresh" content="600"></head></html>`)
+		case "/loop":
+			fmt.Fprint(w, `<html><head><meta http-equiv="refresh" content="0"></head></html>`)
+		}
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{
+		SendReferer:         true,
+		MetaRefreshHandling: true,
+		FollowRedirects:     true,
+		SynchronousRefresh:  true,
+	})
+	err := bow.Open(ts.URL + "/meta")
+	ut.AssertNil(err)
+	ut.AssertEquals("Landing", bow.Title())
+	ut.AssertEquals(ts.URL+"/header", bow.State().Request.Referer())
+
+	err = bow.Open(ts.URL + "/slow")
+	ut.AssertNil(err)
+	ut.AssertEquals("Slow", bow.Title())
+
+	err = bow.Open(ts.URL + "/loop")
+	ut.AssertNotNil(err)
+
+	bow.SetAttribute(SynchronousRefresh, false)
+	err = bow.Open(ts.URL + "/meta")
+	ut.AssertNil(err)
+	ut.AssertEquals("Meta", bow.Title())
+	err = bow.WaitRefresh()
+	ut.AssertNil(err)
+	ut.AssertEquals("Landing", bow.Title())
+	ut.AssertEquals(ts.URL+"/header", bow.State().Request.Referer())
+
+	// The refresh is followed without waiting for it.
+	err = bow.Open(ts.URL + "/auto")
+	ut.AssertNil(err)
+	ut.AssertEquals("/done", <-refreshed)
+	ut.AssertNil(bow.WaitRefresh())
+	ut.AssertEquals("Done", bow.Title())
+
+	// Loading another page cancels the refresh.
+	err = bow.Open(ts.URL + "/slow")
+	ut.AssertNil(err)
+	err = bow.Open(ts.URL + "/landing")
+	ut.AssertNil(err)
+	ut.AssertNil(bow.WaitRefresh())
+	ut.AssertEquals("Landing", bow.Title())
+
+	err = bow.Open(ts.URL + "/loop")
+	ut.AssertNil(err)
+	for i := 0; err == nil && i <= RefreshLimit; i++ {
+		err = bow.WaitRefresh()
+	}
+	ut.AssertNotNil(err)
+
+	bow.SetAttribute(MetaRefreshHandling, false)
+	err = bow.Open(ts.URL + "/meta")
+	ut.AssertNil(err)
+	ut.AssertNil(bow.WaitRefresh())
+	ut.AssertEquals("Meta", bow.Title())
+}
+
+func TestWaitRefresh(t *testing.T) {
+	ut.Run(t)
+	started, release := make(chan bool), make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Start</title><meta http-equiv="refresh" content="0; url=/a"></head></html>`)
+		case "/a":
+			fmt.Fprint(w, `<html><head><title>A</title><meta http-equiv="refresh" content="0; url=/b"></head></html>`)
+		case "/b":
+			fmt.Fprint(w, `<html><head><title>B</title></head></html>`)
+		case "/block":
+			started <- true
+			<-release
+			fmt.Fprint(w, `<html><head><title>Block</title></head></html>`)
+		case "/refresh-block":
+			fmt.Fprint(w, `<html><head><meta http-equiv="refresh" content="0; url=/block"></head></html>`)
+		case "/other":
+			fmt.Fprint(w, `<html><head><title>Other</title></head></html>`)
+		}
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{
+		MetaRefreshHandling: true,
+		FollowRedirects:     true,
+	})
+
+	// The refreshes of the pages loaded by refreshes are waited for.
+	ut.AssertNil(bow.Open(ts.URL))
+	ut.AssertNil(bow.WaitRefresh())
+	ut.AssertEquals("B", bow.Title())
+
+	// Loading a page waits for the refresh which is loading.
+	ut.AssertNil(bow.Open(ts.URL + "/refresh-block"))
+	<-started
+	done := make(chan error)
+	go func() {
+		done <- bow.Open(ts.URL + "/other")
+	}()
+	release <- true
+	ut.AssertNil(<-done)
+	ut.AssertNil(bow.WaitRefresh())
+	ut.AssertEquals("Other", bow.Title())
+}
@@ -0,0 +1,656 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/surf/errors"
+	"golang.org/x/net/html/atom"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// ItemSource describes where a structured data item was found.
+type ItemSource string
+
+const (
+	// JSONLDSource describes items found in application/ld+json scripts.
+	JSONLDSource ItemSource = "json-ld"
+
+	// MicrodataSource describes items found in itemscope/itemprop attributes.
+	MicrodataSource ItemSource = "microdata"
+
+	// RDFaSource describes items found in RDFa Lite typeof/property attributes.
+	RDFaSource ItemSource = "rdfa"
+)
+
+// Item is a structured data item embedded in a page, such as a schema.org
+// Product or Article.
+type Item struct {
+	// Source describes where the item was found.
+	Source ItemSource
+
+	// Types holds the item types, eg "https://schema.org/Product". Microdata and
+	// RDFa types are full IRIs, while JSON-LD types are kept as written.
+	Types []string
+
+	// ID is the global identifier of the item when one is given.
+	ID string
+
+	// Properties maps the property names to their values. Each value is either
+	// a string or a nested *Item.
+	Properties map[string][]interface{}
+}
+
+// newItem creates and returns an empty *Item.
+func newItem(source ItemSource) *Item {
+	return &Item{Source: source, Properties: make(map[string][]interface{})}
+}
+
+// Is returns true when the item has the given type. Types are matched by their
+// full IRI or by their name, so "Product" matches "https://schema.org/Product".
+func (it *Item) Is(typ string) bool {
+	for _, t := range it.Types {
+		if t == typ || typeName(t) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the first string value of the property, or an empty string.
+func (it *Item) Get(prop string) string {
+	for _, v := range it.Properties[prop] {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Strings returns every string value of the property.
+func (it *Item) Strings(prop string) []string {
+	var vals []string
+	for _, v := range it.Properties[prop] {
+		if s, ok := v.(string); ok {
+			vals = append(vals, s)
+		}
+	}
+	return vals
+}
+
+// Item returns the first nested item value of the property, or nil.
+func (it *Item) Item(prop string) *Item {
+	for _, v := range it.Properties[prop] {
+		if item, ok := v.(*Item); ok {
+			return item
+		}
+	}
+	return nil
+}
+
+// Items returns every nested item value of the property.
+func (it *Item) Items(prop string) []*Item {
+	var items []*Item
+	for _, v := range it.Properties[prop] {
+		if item, ok := v.(*Item); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// add appends a value to the property.
+func (it *Item) add(prop string, v interface{}) {
+	it.Properties[prop] = append(it.Properties[prop], v)
+}
+
+// StructuredData holds all of the structured data embedded in a page.
+type StructuredData struct {
+	// Items holds the top level JSON-LD, Microdata and RDFa items.
+	Items []*Item
+
+	// OpenGraph maps OpenGraph meta properties, eg "og:title", to their values.
+	OpenGraph map[string][]string
+
+	// Twitter maps Twitter card meta names, eg "twitter:card", to their values.
+	Twitter map[string][]string
+
+	// Errors holds the errors found while parsing JSON-LD scripts.
+	Errors []error
+}
+
+// ItemsOfType returns the items with the given type. Nested items are searched
+// as well as the top level items.
+func (sd *StructuredData) ItemsOfType(typ string) []*Item {
+	var found []*Item
+	var walk func(items []*Item)
+	walk = func(items []*Item) {
+		for _, it := range items {
+			if it.Is(typ) {
+				found = append(found, it)
+			}
+			for _, vals := range it.Properties {
+				for _, v := range vals {
+					if nested, ok := v.(*Item); ok {
+						walk([]*Item{nested})
+					}
+				}
+			}
+		}
+	}
+	walk(sd.Items)
+	return found
+}
+
+// StructuredData returns the JSON-LD, Microdata, RDFa, OpenGraph and Twitter
+// card data embedded in the page. URL values are resolved against RelativeUrl.
+//
+// JSON-LD scripts which can't be parsed are skipped, and their errors are
+// kept in the Errors field.
+func (bow *Browser) StructuredData() *StructuredData {
+	items, errs := bow.jsonLD()
+	items = append(items, bow.Microdata()...)
+	items = append(items, bow.RDFa()...)
+	return &StructuredData{
+		Items:     items,
+		OpenGraph: bow.OpenGraph(),
+		Twitter:   bow.TwitterCard(),
+		Errors:    errs,
+	}
+}
+
+// JSONLD returns the items found in the application/ld+json scripts of the page.
+// Scripts which can't be parsed are skipped, and the first error is returned
+// along with the items from the other scripts.
+func (bow *Browser) JSONLD() ([]*Item, error) {
+	items, errs := bow.jsonLD()
+	if len(errs) > 0 {
+		return items, errs[0]
+	}
+	return items, nil
+}
+
+// jsonLD returns the JSON-LD items in the page and every parse error.
+func (bow *Browser) jsonLD() ([]*Item, []error) {
+	var items []*Item
+	var errs []error
+	bow.Find("script[type]").Each(func(_ int, s *goquery.Selection) {
+		typ, _ := s.Attr("type")
+		if mt, _, err := mime.ParseMediaType(typ); err != nil || mt != "application/ld+json" {
+			return
+		}
+		dec := json.NewDecoder(strings.NewReader(s.Text()))
+		dec.UseNumber()
+		var data interface{}
+		if err := dec.Decode(&data); err != nil {
+			errs = append(errs, errors.New("Invalid JSON-LD script: %s", err).With(errors.Fields{Err: err}))
+			return
+		}
+		items = append(items, bow.jsonLDItems(data)...)
+	})
+	return items, errs
+}
+
+// jsonLDItems converts a decoded JSON-LD document into items. Graphs and
+// arrays of items are flattened into a list of top level items.
+func (bow *Browser) jsonLDItems(data interface{}) []*Item {
+	switch v := data.(type) {
+	case []interface{}:
+		var items []*Item
+		for _, d := range v {
+			items = append(items, bow.jsonLDItems(d)...)
+		}
+		return items
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return bow.jsonLDItems(graph)
+		}
+		return []*Item{bow.jsonLDItem(v)}
+	}
+	return nil
+}
+
+// jsonLDItem converts a JSON-LD node object into an item.
+func (bow *Browser) jsonLDItem(obj map[string]interface{}) *Item {
+	item := newItem(JSONLDSource)
+	for key, val := range obj {
+		switch key {
+		case "@context":
+			continue
+		case "@type":
+			item.Types = append(item.Types, jsonStrings(val)...)
+		case "@id":
+			if id, ok := val.(string); ok {
+				item.ID = bow.resolveIRI(id)
+			}
+		default:
+			for _, v := range jsonValues(val) {
+				switch vv := v.(type) {
+				case map[string]interface{}:
+					if lit, ok := vv["@value"]; ok {
+						item.add(key, jsonString(lit))
+					} else {
+						item.add(key, bow.jsonLDItem(vv))
+					}
+				default:
+					s := jsonString(vv)
+					if isURLProperty(key) {
+						s = bow.resolveIRI(s)
+					}
+					item.add(key, s)
+				}
+			}
+		}
+	}
+	return item
+}
+
+// Microdata returns the top level items described with the itemscope and
+// itemprop attributes.
+func (bow *Browser) Microdata() []*Item {
+	var items []*Item
+	bow.Find("[itemscope]").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("itemprop"); !ok {
+			items = append(items, bow.microdataItem(s.Get(0), nil))
+		}
+	})
+	return items
+}
+
+// microdataItem builds the item for an element with the itemscope attribute.
+// The visited map guards against itemref loops.
+func (bow *Browser) microdataItem(n *nethtml.Node, visited map[*nethtml.Node]bool) *Item {
+	if visited == nil {
+		visited = make(map[*nethtml.Node]bool)
+	}
+	visited[n] = true
+
+	item := newItem(MicrodataSource)
+	if types, ok := nodeAttr(n, "itemtype"); ok {
+		item.Types = strings.Fields(types)
+	}
+	if id, ok := nodeAttr(n, "itemid"); ok {
+		item.ID = bow.resolveIRI(strings.TrimSpace(id))
+	}
+
+	var pending []*nethtml.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		pending = append(pending, c)
+	}
+	if refs, ok := nodeAttr(n, "itemref"); ok {
+		root := n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		ids := make(map[string]*nethtml.Node)
+		indexIds(root, ids)
+		for _, ref := range strings.Fields(refs) {
+			if el, ok := ids[ref]; ok {
+				pending = append(pending, el)
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		el := pending[0]
+		pending = pending[1:]
+		if el.Type != nethtml.ElementNode || visited[el] {
+			continue
+		}
+		_, scope := nodeAttr(el, "itemscope")
+		if props, ok := nodeAttr(el, "itemprop"); ok {
+			var val interface{}
+			if scope {
+				val = bow.microdataItem(el, visited)
+			} else {
+				val = bow.microdataValue(el)
+			}
+			for _, prop := range strings.Fields(props) {
+				item.add(prop, val)
+			}
+		}
+		if !scope {
+			var children []*nethtml.Node
+			for c := el.FirstChild; c != nil; c = c.NextSibling {
+				children = append(children, c)
+			}
+			pending = append(children, pending...)
+		}
+	}
+	return item
+}
+
+// microdataValue returns the value of an itemprop element which is not an item.
+func (bow *Browser) microdataValue(n *nethtml.Node) string {
+	switch n.DataAtom {
+	case atom.Meta:
+		v, _ := nodeAttr(n, "content")
+		return v
+	case atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video:
+		return bow.urlAttr(n, "src")
+	case atom.A, atom.Area, atom.Link:
+		return bow.urlAttr(n, "href")
+	case atom.Object:
+		return bow.urlAttr(n, "data")
+	case atom.Data, atom.Meter:
+		v, _ := nodeAttr(n, "value")
+		return v
+	case atom.Time:
+		if v, ok := nodeAttr(n, "datetime"); ok {
+			return v
+		}
+	}
+	return strings.TrimSpace(nodeText(n))
+}
+
+// RDFa returns the top level items described with the RDFa Lite vocab,
+// prefix, typeof and property attributes.
+//
+// Types are expanded into full IRIs. Property names are kept as written,
+// except CURIEs such as "schema:name" which are expanded with the prefixes
+// declared by the element or its ancestors.
+func (bow *Browser) RDFa() []*Item {
+	var items []*Item
+	bow.Find("[typeof]").Each(func(_ int, s *goquery.Selection) {
+		n := s.Get(0)
+		if _, ok := nodeAttr(n, "property"); ok && rdfaScope(n) != nil {
+			return
+		}
+		items = append(items, bow.rdfaItem(n))
+	})
+	return items
+}
+
+// rdfaItem builds the item for an element with the typeof attribute.
+func (bow *Browser) rdfaItem(n *nethtml.Node) *Item {
+	item := newItem(RDFaSource)
+	vocab, prefixes := rdfaVocab(n), rdfaPrefixes(n)
+	if types, ok := nodeAttr(n, "typeof"); ok {
+		for _, t := range strings.Fields(types) {
+			item.Types = append(item.Types, expandTerm(vocab, prefixes, t))
+		}
+	}
+	if id, ok := nodeAttr(n, "resource"); ok {
+		item.ID = bow.resolveIRI(strings.TrimSpace(id))
+	}
+
+	var walk func(el *nethtml.Node)
+	walk = func(el *nethtml.Node) {
+		for c := el.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != nethtml.ElementNode {
+				continue
+			}
+			_, typed := nodeAttr(c, "typeof")
+			if props, ok := nodeAttr(c, "property"); ok {
+				var val interface{}
+				if typed {
+					val = bow.rdfaItem(c)
+				} else {
+					val = bow.rdfaValue(c)
+				}
+				prefixes := rdfaPrefixes(c)
+				for _, prop := range strings.Fields(props) {
+					item.add(expandCURIE(prefixes, prop), val)
+				}
+			}
+			if !typed {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return item
+}
+
+// rdfaValue returns the value of a property element which is not an item.
+func (bow *Browser) rdfaValue(n *nethtml.Node) string {
+	if v, ok := nodeAttr(n, "content"); ok {
+		return v
+	}
+	for _, name := range []string{"resource", "href", "src"} {
+		if _, ok := nodeAttr(n, name); ok {
+			return bow.urlAttr(n, name)
+		}
+	}
+	if n.DataAtom == atom.Time {
+		if v, ok := nodeAttr(n, "datetime"); ok {
+			return v
+		}
+	}
+	return strings.TrimSpace(nodeText(n))
+}
+
+// rdfaScope returns the nearest ancestor with the typeof attribute.
+func rdfaScope(n *nethtml.Node) *nethtml.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if _, ok := nodeAttr(p, "typeof"); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// rdfaVocab returns the vocab in effect for the element.
+func rdfaVocab(n *nethtml.Node) string {
+	for p := n; p != nil; p = p.Parent {
+		if v, ok := nodeAttr(p, "vocab"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// rdfaPrefixes returns the prefixes declared by the element and its ancestors,
+// which map a prefix such as "schema" to its IRI. The nearest declaration of a
+// prefix is used.
+func rdfaPrefixes(n *nethtml.Node) map[string]string {
+	prefixes := make(map[string]string)
+	for p := n; p != nil; p = p.Parent {
+		v, ok := nodeAttr(p, "prefix")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(v)
+		for i := 0; i+1 < len(fields); i += 2 {
+			name := strings.ToLower(strings.TrimSuffix(fields[i], ":"))
+			if !strings.HasSuffix(fields[i], ":") || name == "" {
+				break
+			}
+			if _, ok := prefixes[name]; !ok {
+				prefixes[name] = fields[i+1]
+			}
+		}
+	}
+	return prefixes
+}
+
+// expandTerm prefixes a term with the vocab, and expands a CURIE with the
+// declared prefixes. IRIs and CURIEs with an unknown prefix are unchanged.
+func expandTerm(vocab string, prefixes map[string]string, term string) string {
+	if strings.Contains(term, ":") {
+		return expandCURIE(prefixes, term)
+	}
+	if vocab == "" {
+		return term
+	}
+	return vocab + term
+}
+
+// expandCURIE expands a CURIE such as "schema:Person" into a full IRI when
+// its prefix is declared. Other values are returned unchanged.
+func expandCURIE(prefixes map[string]string, term string) string {
+	i := strings.Index(term, ":")
+	if i == -1 || strings.HasPrefix(term[i+1:], "//") {
+		return term
+	}
+	if iri, ok := prefixes[strings.ToLower(term[:i])]; ok {
+		return iri + term[i+1:]
+	}
+	return term
+}
+
+// OpenGraph returns the OpenGraph meta properties of the page, such as
+// "og:title" and "og:image". URL values are resolved against RelativeUrl.
+func (bow *Browser) OpenGraph() map[string][]string {
+	props := make(map[string][]string)
+	bow.Find("meta[property]").Each(func(_ int, s *goquery.Selection) {
+		prop, _ := s.Attr("property")
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		if !isOpenGraphProperty(prop) {
+			return
+		}
+		content, _ := s.Attr("content")
+		if isURLProperty(prop) {
+			content = bow.resolveIRI(content)
+		}
+		props[prop] = append(props[prop], content)
+	})
+	return props
+}
+
+// TwitterCard returns the Twitter card meta values of the page, such as
+// "twitter:card" and "twitter:image". URL values are resolved against RelativeUrl.
+func (bow *Browser) TwitterCard() map[string][]string {
+	props := make(map[string][]string)
+	bow.Find("meta[name],meta[property]").Each(func(_ int, s *goquery.Selection) {
+		name, ok := s.Attr("name")
+		if !ok {
+			name, _ = s.Attr("property")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !strings.HasPrefix(name, "twitter:") {
+			return
+		}
+		content, ok := s.Attr("content")
+		if !ok {
+			content, _ = s.Attr("value")
+		}
+		if isURLProperty(name) {
+			content = bow.resolveIRI(content)
+		}
+		props[name] = append(props[name], content)
+	})
+	return props
+}
+
+// openGraphPrefixes are the namespaces used by OpenGraph meta properties.
+var openGraphPrefixes = []string{
+	"og:", "fb:", "article:", "book:", "books:", "business:", "music:",
+	"place:", "product:", "profile:", "restaurant:", "video:",
+}
+
+// isOpenGraphProperty returns true when the meta property is an OpenGraph property.
+func isOpenGraphProperty(prop string) bool {
+	for _, prefix := range openGraphPrefixes {
+		if strings.HasPrefix(prop, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlProperties are the names of properties holding URLs, which are resolved
+// against the page URL.
+var urlProperties = map[string]bool{
+	"url": true, "image": true, "logo": true, "sameas": true, "contenturl": true,
+	"thumbnailurl": true, "embedurl": true, "mainentityofpage": true,
+	"og:image": true, "og:video": true, "og:audio": true,
+	"twitter:image": true, "twitter:image:src": true, "twitter:player": true,
+	"twitter:player:stream": true,
+}
+
+// isURLProperty returns true when the property holds a URL.
+func isURLProperty(prop string) bool {
+	prop = strings.ToLower(prop)
+	return urlProperties[prop] || strings.HasSuffix(prop, ":url") || strings.HasSuffix(prop, ":secure_url")
+}
+
+// resolveIRI resolves a possibly relative URL against RelativeUrl. Blank node
+// identifiers and values which aren't URLs are returned unchanged.
+func (bow *Browser) resolveIRI(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasPrefix(s, "_:") || bow.RelativeUrl() == nil {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return bow.ResolveUrl(u).String()
+}
+
+// urlAttr returns the named attribute of the element resolved as a URL.
+func (bow *Browser) urlAttr(n *nethtml.Node, name string) string {
+	v, _ := nodeAttr(n, name)
+	return bow.resolveIRI(v)
+}
+
+// typeName returns the last path segment or fragment of a type IRI.
+func typeName(t string) string {
+	if i := strings.LastIndexAny(t, "/#"); i != -1 {
+		return t[i+1:]
+	}
+	return t
+}
+
+// nodeText returns the text content of the node.
+func nodeText(n *nethtml.Node) string {
+	var buf bytes.Buffer
+	var walk func(*nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type == nethtml.TextNode {
+			buf.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return buf.String()
+}
+
+// jsonValues returns the decoded JSON value as a list of values.
+func jsonValues(v interface{}) []interface{} {
+	if vals, ok := v.([]interface{}); ok {
+		return vals
+	}
+	if v == nil {
+		return nil
+	}
+	return []interface{}{v}
+}
+
+// jsonStrings returns the decoded JSON string or array of strings as a list.
+func jsonStrings(v interface{}) []string {
+	var ss []string
+	for _, val := range jsonValues(v) {
+		if s, ok := val.(string); ok {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+// jsonString converts a decoded JSON scalar into a string.
+func jsonString(v interface{}) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case json.Number:
+		return vv.String()
+	case bool:
+		if vv {
+			return "true"
+		}
+		return "false"
+	case nil:
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
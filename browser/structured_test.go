@@ -0,0 +1,128 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/headzoo/ut"
+)
+
+var htmlStructured = `<!doctype html>
+<html>
+	<head>
+		<title>Surf Board</title>
+		<meta property="og:title" content="Surf Board" />
+		<meta property="og:image" content="/img/board.png" />
+		<meta property="og:image:secure_url" content="https://cdn.example.com/board.png" />
+		<meta property="product:price:amount" content="199.95" />
+		<meta name="twitter:card" content="summary" />
+		<meta name="twitter:image" content="img/card.png" />
+		<script type="application/LD+JSON; charset=utf-8">
+		{
+			"@context": "https://schema.org",
+			"@graph": [
+				{
+					"@type": "Product",
+					"@id": "#product",
+					"name": "Surf Board",
+					"image": ["/img/board.png", "/img/board2.png"],
+					"offers": {"@type": "Offer", "price": 199.95, "availability": "InStock", "url": "buy"}
+				},
+				{"@type": ["Organization", "Brand"], "name": "Headzoo", "logo": "/logo.png"}
+			]
+		}
+		</script>
+		<script type="application/ld+json">{ invalid </script>
+	</head>
+	<body>
+		<div itemscope itemtype="https://schema.org/Article" itemref="byline">
+			<h1 itemprop="headline name">Catching Waves</h1>
+			<a itemprop="url" href="/articles/waves">Permalink</a>
+			<time itemprop="datePublished" datetime="2017-03-18">March 18</time>
+			<div itemprop="publisher" itemscope itemtype="https://schema.org/Organization">
+				<span itemprop="name">Surf Weekly</span>
+				<img itemprop="logo" src="logo.png" />
+			</div>
+		</div>
+		<p id="byline">By <span itemprop="author">Sean</span></p>
+
+		<div vocab="https://schema.org/" typeof="Event">
+			<span property="name">Surf Contest</span>
+			<a property="url" href="/events/1">Details</a>
+			<meta property="startDate" content="2017-06-01" />
+			<div property="location" typeof="Place">
+				<span property="name">The Beach</span>
+			</div>
+		</div>
+
+		<div prefix="schema: https://schema.org/" typeof="schema:Person">
+			<span property="schema:name">Sean</span>
+		</div>
+	</body>
+</html>`
+
+func TestStructuredData(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlStructured))
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL + "/shop/")
+	ut.AssertNil(err)
+
+	sd := bow.StructuredData()
+	ut.AssertEquals(1, len(sd.Errors))
+	ut.AssertEquals(5, len(sd.Items))
+
+	products := sd.ItemsOfType("Product")
+	ut.AssertEquals(1, len(products))
+	product := products[0]
+	ut.AssertEquals(JSONLDSource, product.Source)
+	ut.AssertEquals(ts.URL+"/shop/#product", product.ID)
+	ut.AssertEquals("Surf Board", product.Get("name"))
+	ut.AssertEquals([]string{ts.URL + "/img/board.png", ts.URL + "/img/board2.png"}, product.Strings("image"))
+	offer := product.Item("offers")
+	ut.AssertTrue(offer.Is("Offer"))
+	ut.AssertEquals("199.95", offer.Get("price"))
+	ut.AssertEquals(ts.URL+"/shop/buy", offer.Get("url"))
+	ut.AssertTrue(sd.ItemsOfType("Brand")[0].Is("Organization"))
+
+	articles := sd.ItemsOfType("https://schema.org/Article")
+	ut.AssertEquals(1, len(articles))
+	article := articles[0]
+	ut.AssertEquals(MicrodataSource, article.Source)
+	ut.AssertEquals("Catching Waves", article.Get("headline"))
+	ut.AssertEquals("Catching Waves", article.Get("name"))
+	ut.AssertEquals(ts.URL+"/articles/waves", article.Get("url"))
+	ut.AssertEquals("2017-03-18", article.Get("datePublished"))
+	ut.AssertEquals("Sean", article.Get("author"))
+	ut.AssertEquals("Surf Weekly", article.Item("publisher").Get("name"))
+	ut.AssertEquals(ts.URL+"/shop/logo.png", article.Item("publisher").Get("logo"))
+	ut.AssertEquals(2, len(sd.ItemsOfType("Organization")))
+
+	rdfa := bow.RDFa()
+	ut.AssertEquals(2, len(rdfa))
+	event := rdfa[0]
+	ut.AssertEquals([]string{"https://schema.org/Event"}, event.Types)
+	ut.AssertEquals("Surf Contest", event.Get("name"))
+	ut.AssertEquals(ts.URL+"/events/1", event.Get("url"))
+	ut.AssertEquals("2017-06-01", event.Get("startDate"))
+	ut.AssertTrue(event.Item("location").Is("Place"))
+	ut.AssertEquals("The Beach", event.Item("location").Get("name"))
+	person := rdfa[1]
+	ut.AssertEquals([]string{"https://schema.org/Person"}, person.Types)
+	ut.AssertEquals("Sean", person.Get("https://schema.org/name"))
+
+	ut.AssertEquals([]string{"Surf Board"}, sd.OpenGraph["og:title"])
+	ut.AssertEquals([]string{ts.URL + "/img/board.png"}, sd.OpenGraph["og:image"])
+	ut.AssertEquals([]string{"https://cdn.example.com/board.png"}, sd.OpenGraph["og:image:secure_url"])
+	ut.AssertEquals([]string{"199.95"}, sd.OpenGraph["product:price:amount"])
+	ut.AssertEquals([]string{"summary"}, sd.Twitter["twitter:card"])
+	ut.AssertEquals([]string{ts.URL + "/shop/img/card.png"}, sd.Twitter["twitter:image"])
+
+	_, err = bow.JSONLD()
+	ut.AssertNotNil(err)
+}
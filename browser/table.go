@@ -0,0 +1,345 @@
+package browser
+
+import (
+	"encoding/csv"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxColSpan and maxRowSpan are the largest colspan and rowspan values that
+// are honored. Larger values are clamped, the same as the HTML specification
+// clamps them.
+const (
+	maxColSpan = 1000
+	maxRowSpan = 65534
+)
+
+// Table is an HTML table normalized into a grid of cells.
+//
+// Cells spanning several rows or columns are repeated in every slot they
+// cover, so every row has the same number of cells.
+type Table struct {
+	// Caption is the text of the table caption.
+	Caption string
+
+	// Headers holds the text of the header for each column. Tables with several
+	// header rows have the distinct texts of each column joined with " / ".
+	Headers []string
+
+	// HeaderRows holds the rows making up the header of the table.
+	HeaderRows [][]*TableCell
+
+	// Rows holds the body rows of the table.
+	Rows [][]*TableCell
+}
+
+// TableCell is a single slot in a table grid.
+type TableCell struct {
+	// Text is the text of the cell, with runs of white space collapsed.
+	Text string
+
+	// Header is true when the cell is a th element.
+	Header bool
+
+	// Links holds the href of every link inside the cell.
+	Links []*url.URL
+
+	// RowSpan and ColSpan are the number of rows and columns the cell covers.
+	RowSpan int
+	ColSpan int
+
+	// Spanned is true when the slot is covered by a cell which starts in an
+	// earlier row or column.
+	Spanned bool
+
+	// Selection is the td or th element.
+	Selection *goquery.Selection
+}
+
+// NewTable creates and returns a *Table from the first table element in the
+// selection. Links in the cells are not resolved.
+func NewTable(sel *goquery.Selection) *Table {
+	return newTable(sel.First(), func(u *url.URL) *url.URL { return u })
+}
+
+// Tables returns every table in the page.
+func (bow *Browser) Tables() []*Table {
+	return bow.TablesIn(bow.Dom())
+}
+
+// TablesIn returns every table matched by the selection or found inside of it.
+// Links in the cells are resolved with ResolveUrl.
+func (bow *Browser) TablesIn(sel *goquery.Selection) []*Table {
+	var tables []*Table
+	sel.Filter("table").AddSelection(sel.Find("table")).Each(func(_ int, s *goquery.Selection) {
+		tables = append(tables, newTable(s, bow.ResolveUrl))
+	})
+	return tables
+}
+
+// Strings returns the text of the body cells.
+func (t *Table) Strings() [][]string {
+	rows := make([][]string, len(t.Rows))
+	for i, row := range t.Rows {
+		rows[i] = make([]string, len(row))
+		for j, cell := range row {
+			rows[i][j] = cell.Text
+		}
+	}
+	return rows
+}
+
+// Records returns the body rows as maps of the column headers to the cell text.
+// Columns without a header are named "Column N", and repeated header names are
+// followed by their column number.
+func (t *Table) Records() []map[string]string {
+	keys := t.keys()
+	records := make([]map[string]string, len(t.Rows))
+	for i, row := range t.Rows {
+		records[i] = make(map[string]string, len(row))
+		for j, cell := range row {
+			records[i][keys[j]] = cell.Text
+		}
+	}
+	return records
+}
+
+// WriteCSV writes the table to w in CSV format. The headers are written as
+// the first record when the table has a header.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if len(t.Headers) > 0 {
+		if err := cw.Write(t.Headers); err != nil {
+			return err
+		}
+	}
+	if err := cw.WriteAll(t.Strings()); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// CSV returns the table in CSV format.
+func (t *Table) CSV() (string, error) {
+	var buf strings.Builder
+	err := t.WriteCSV(&buf)
+	return buf.String(), err
+}
+
+// keys returns the unique names used as record keys for each column.
+func (t *Table) keys() []string {
+	width := len(t.Headers)
+	for _, row := range t.Rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	keys := make([]string, width)
+	seen := make(map[string]bool, width)
+	for i := range keys {
+		key := ""
+		if i < len(t.Headers) {
+			key = t.Headers[i]
+		}
+		if key == "" {
+			key = "Column " + strconv.Itoa(i+1)
+		}
+		if seen[key] {
+			key = key + " " + strconv.Itoa(i+1)
+		}
+		seen[key] = true
+		keys[i] = key
+	}
+	return keys
+}
+
+// tableRow is a row of a table before it's placed in the grid.
+type tableRow struct {
+	sel    *goquery.Selection
+	header bool
+
+	// group is the row group the row belongs to. Rows of a thead, tbody or
+	// tfoot are a group, and so are consecutive rows directly in the table.
+	group int
+}
+
+// newTable builds the grid for the table element. The resolve function is used
+// to create absolute URLs for the cell links.
+func newTable(table *goquery.Selection, resolve func(*url.URL) *url.URL) *Table {
+	t := &Table{
+		Caption: collapseSpace(table.ChildrenFiltered("caption").First().Text()),
+	}
+
+	// Rows are taken from the thead first and the tfoot last, the same order
+	// they are displayed in. Rows of nested tables are not included.
+	var rows []tableRow
+	group := 0
+	addGroup := func(s *goquery.Selection, header bool) {
+		group++
+		s.ChildrenFiltered("tr").Each(func(_ int, tr *goquery.Selection) {
+			rows = append(rows, tableRow{sel: tr, header: header, group: group})
+		})
+	}
+	table.ChildrenFiltered("thead").Each(func(_ int, s *goquery.Selection) {
+		addGroup(s, true)
+	})
+	table.Children().Each(func(_ int, s *goquery.Selection) {
+		if s.Is("tr") {
+			if len(rows) == 0 || rows[len(rows)-1].sel.Parent().Get(0) != table.Get(0) {
+				group++
+			}
+			rows = append(rows, tableRow{sel: s, group: group})
+		} else if s.Is("tbody") {
+			addGroup(s, false)
+		}
+	})
+	table.ChildrenFiltered("tfoot").Each(func(_ int, s *goquery.Selection) {
+		addGroup(s, false)
+	})
+
+	// Cells never span past the end of their row group, and a rowspan of zero
+	// spans to the end of it.
+	groupEnd := make([]int, len(rows))
+	for r := len(rows) - 1; r >= 0; r-- {
+		groupEnd[r] = r + 1
+		if r+1 < len(rows) && rows[r+1].group == rows[r].group {
+			groupEnd[r] = groupEnd[r+1]
+		}
+	}
+
+	grid := make([][]*TableCell, len(rows))
+	width := 0
+	for r, row := range rows {
+		col := 0
+		row.sel.ChildrenFiltered("td,th").Each(func(_ int, s *goquery.Selection) {
+			for col < len(grid[r]) && grid[r][col] != nil {
+				col++
+			}
+			cell := newTableCell(s, resolve)
+			if cell.RowSpan == 0 {
+				cell.RowSpan = groupEnd[r] - r
+			}
+			for dr := 0; dr < cell.RowSpan && r+dr < groupEnd[r]; dr++ {
+				for dc := 0; dc < cell.ColSpan; dc++ {
+					slot := cell
+					if dr > 0 || dc > 0 {
+						copied := *cell
+						copied.Spanned = true
+						slot = &copied
+					}
+					grid[r+dr] = placeCell(grid[r+dr], col+dc, slot)
+				}
+			}
+			col += cell.ColSpan
+		})
+		if len(grid[r]) > width {
+			width = len(grid[r])
+		}
+	}
+	for r := range grid {
+		for c := 0; c < width; c++ {
+			if c >= len(grid[r]) || grid[r][c] == nil {
+				grid[r] = placeCell(grid[r], c, &TableCell{RowSpan: 1, ColSpan: 1})
+			}
+		}
+	}
+
+	// Without a thead, leading rows made up only of th cells are the header.
+	headers := 0
+	for headers < len(rows) && rows[headers].header {
+		headers++
+	}
+	if headers == 0 {
+		for headers < len(rows) && isHeaderRow(grid[headers]) {
+			headers++
+		}
+	}
+	t.HeaderRows = grid[:headers]
+	t.Rows = grid[headers:]
+	if headers > 0 {
+		t.Headers = make([]string, width)
+		for c := 0; c < width; c++ {
+			var texts []string
+			for _, row := range t.HeaderRows {
+				text := row[c].Text
+				if text != "" && (len(texts) == 0 || texts[len(texts)-1] != text) {
+					texts = append(texts, text)
+				}
+			}
+			t.Headers[c] = strings.Join(texts, " / ")
+		}
+	}
+
+	return t
+}
+
+// newTableCell creates a *TableCell from a td or th element.
+func newTableCell(s *goquery.Selection, resolve func(*url.URL) *url.URL) *TableCell {
+	cell := &TableCell{
+		Text:      collapseSpace(s.Text()),
+		Header:    s.Is("th"),
+		RowSpan:   spanAttr(s, "rowspan", 1, 0, maxRowSpan),
+		ColSpan:   spanAttr(s, "colspan", 1, 1, maxColSpan),
+		Selection: s,
+	}
+	s.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		if u, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			cell.Links = append(cell.Links, resolve(u))
+		}
+	})
+	return cell
+}
+
+// placeCell sets the slot at col of the row, growing the row when needed.
+func placeCell(row []*TableCell, col int, cell *TableCell) []*TableCell {
+	for len(row) <= col {
+		row = append(row, nil)
+	}
+	row[col] = cell
+	return row
+}
+
+// spanAttr reads a rowspan or colspan attribute. The default is returned when
+// the attribute is missing or invalid, and values are clamped to min and max.
+func spanAttr(s *goquery.Selection, name string, def, min, max int) int {
+	v, ok := s.Attr(name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return def
+	}
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// isHeaderRow returns true when every cell in the row is a th element.
+func isHeaderRow(row []*TableCell) bool {
+	found := false
+	for _, cell := range row {
+		if cell.Selection == nil {
+			continue
+		}
+		if !cell.Header {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// collapseSpace trims the string and replaces runs of white space with a single space.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
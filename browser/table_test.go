@@ -0,0 +1,123 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/ut"
+)
+
+var htmlTables = `<!doctype html>
+<html>
+	<body>
+		<table id="scores">
+			<caption> Season   scores </caption>
+			<thead>
+				<tr><th rowspan="2">Team</th><th colspan="2">Games</th></tr>
+				<tr><th>Won</th><th>Lost</th></tr>
+			</thead>
+			<tfoot>
+				<tr><td>Total</td><td>5</td><td>3</td></tr>
+			</tfoot>
+			<tbody>
+				<tr><td><a href="/teams/red">Red   Team</a></td><td rowspan="2">2</td><td>1</td></tr>
+				<tr><td><a href="blue">Blue, "B"</a></td><td>2</td></tr>
+				<tr><td colspan="3">Cancelled</td></tr>
+			</tbody>
+		</table>
+		<table id="plain">
+			<tr><th>Name</th><th>Name</th><th></th></tr>
+			<tr><td>a</td><td>b</td></tr>
+		</table>
+	</body>
+</html>`
+
+func TestTables(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlTables))
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL + "/league/")
+	ut.AssertNil(err)
+
+	tables := bow.Tables()
+	ut.AssertEquals(2, len(tables))
+
+	table := tables[0]
+	ut.AssertEquals("Season scores", table.Caption)
+	ut.AssertEquals([]string{"Team", "Games / Won", "Games / Lost"}, table.Headers)
+	ut.AssertEquals([][]string{
+		{"Red Team", "2", "1"},
+		{`Blue, "B"`, "2", "2"},
+		{"Cancelled", "Cancelled", "Cancelled"},
+		{"Total", "5", "3"},
+	}, table.Strings())
+	ut.AssertTrue(table.Rows[1][1].Spanned)
+	ut.AssertFalse(table.Rows[1][2].Spanned)
+	ut.AssertEquals(ts.URL+"/teams/red", table.Rows[0][0].Links[0].String())
+	ut.AssertEquals(ts.URL+"/league/blue", table.Rows[1][0].Links[0].String())
+
+	records := table.Records()
+	ut.AssertEquals("Red Team", records[0]["Team"])
+	ut.AssertEquals("1", records[0]["Games / Lost"])
+
+	csv, err := table.CSV()
+	ut.AssertNil(err)
+	ut.AssertEquals("Team,Games / Won,Games / Lost\nRed Team,2,1\n\"Blue, \"\"B\"\"\",2,2\nCancelled,Cancelled,Cancelled\nTotal,5,3\n", csv)
+
+	table = tables[1]
+	ut.AssertEquals([][]string{{"a", "b", ""}}, table.Strings())
+	ut.AssertEquals(map[string]string{"Name": "a", "Name 2": "b", "Column 3": ""}, table.Records()[0])
+}
+
+func TestNewTable(t *testing.T) {
+	ut.Run(t)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlTables))
+	ut.AssertNil(err)
+
+	table := NewTable(doc.Find("#scores"))
+	ut.AssertEquals("/teams/red", table.Rows[0][0].Links[0].String())
+	ut.AssertEquals(2, len(table.HeaderRows))
+	ut.AssertEquals(4, len(table.Rows))
+
+	bow := newBrowser()
+	ut.AssertEquals(0, len(bow.TablesIn(doc.Find("#missing"))))
+}
+
+func TestTableRowGroups(t *testing.T) {
+	ut.Run(t)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<table>
+		<thead><tr><th rowspan="0">Name</th><th>Score</th></tr></thead>
+		<tbody>
+			<tr><td rowspan="0">Red</td><td>1</td></tr>
+			<tr><td>2</td></tr>
+			<tr><td>3</td></tr>
+		</tbody>
+		<tbody>
+			<tr><td rowspan="100000">Blue</td><td>4</td></tr>
+			<tr><td>5</td></tr>
+		</tbody>
+		<tfoot><tr><td>Total</td><td>15</td></tr></tfoot>
+	</table>`))
+	ut.AssertNil(err)
+
+	table := NewTable(doc.Find("table"))
+	ut.AssertEquals([]string{"Name", "Score"}, table.Headers)
+	ut.AssertEquals([][]string{
+		{"Red", "1"},
+		{"Red", "2"},
+		{"Red", "3"},
+		{"Blue", "4"},
+		{"Blue", "5"},
+		{"Total", "15"},
+	}, table.Strings())
+	ut.AssertEquals(3, table.Rows[0][0].RowSpan)
+	ut.AssertEquals(maxRowSpan, table.Rows[3][0].RowSpan)
+	ut.AssertFalse(table.Rows[5][0].Spanned)
+}
@@ -0,0 +1,469 @@
+package browser
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/atom"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// PlainTextRuleWidth is the number of characters in the line drawn for <hr> elements.
+var PlainTextRuleWidth = 72
+
+// PlainText renders the selection as plain text, the same way a text mode web
+// browser such as lynx displays a page.
+//
+// Block elements start new lines, list items are bulleted or numbered, and
+// tables are laid out in columns. Links are followed by a reference number,
+// eg "Surf[1]", and a list of the referenced URLs is added to the end of the
+// text. Scripts, styles and hidden elements are skipped. Links are not
+// resolved, see Browser.PlainText to resolve them.
+func PlainText(sel *goquery.Selection) string {
+	return renderText(sel, func(u *url.URL) *url.URL { return u })
+}
+
+// PlainText renders the page as plain text. It works just like the PlainText
+// function, except that links are resolved with ResolveUrl.
+func (bow *Browser) PlainText() string {
+	return renderText(bow.Find("body"), bow.ResolveUrl)
+}
+
+// renderText renders every node in the selection, followed by the link references.
+func renderText(sel *goquery.Selection, resolve func(*url.URL) *url.URL) string {
+	r := &textRenderer{resolve: resolve, links: new([]string), lineStart: true}
+	for _, n := range sel.Nodes {
+		r.render(n)
+	}
+	if len(*r.links) > 0 {
+		r.breakLines(2)
+		r.writeLine("References")
+		r.breakLines(2)
+		width := len(strconv.Itoa(len(*r.links)))
+		for i, link := range *r.links {
+			num := strconv.Itoa(i + 1)
+			r.writeLine(strings.Repeat(" ", 3+width-len(num)) + num + ". " + link)
+		}
+	}
+	return cleanText(r.buf.String())
+}
+
+// textRenderer writes the text of nodes to a buffer.
+type textRenderer struct {
+	buf     strings.Builder
+	resolve func(*url.URL) *url.URL
+
+	// links holds the referenced URLs, shared with the renderers of table cells.
+	links *[]string
+
+	// indent is written at the start of every line.
+	indent string
+
+	// newlines is the number of line breaks to write before the next text.
+	newlines int
+
+	// lineStart is true when nothing has been written to the current line.
+	lineStart bool
+
+	// space is true when a space must be written before the next text.
+	space bool
+
+	// pre is greater than zero inside of preformatted elements.
+	pre int
+}
+
+// render writes the text of the node and its descendants.
+func (r *textRenderer) render(n *nethtml.Node) {
+	switch n.Type {
+	case nethtml.TextNode:
+		r.text(n.Data)
+		return
+	case nethtml.DocumentNode:
+		r.children(n)
+		return
+	case nethtml.ElementNode:
+	default:
+		return
+	}
+	if isHiddenElement(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		r.newline()
+	case atom.Hr:
+		r.breakLines(1)
+		r.writeLine(strings.Repeat("-", PlainTextRuleWidth))
+		r.breakLines(1)
+	case atom.P, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Figure:
+		r.breakLines(2)
+		r.children(n)
+		r.breakLines(2)
+	case atom.Pre:
+		r.breakLines(2)
+		r.pre++
+		r.children(n)
+		r.pre--
+		r.breakLines(2)
+	case atom.Blockquote:
+		r.breakLines(2)
+		r.indented("    ", func() { r.children(n) })
+		r.breakLines(2)
+	case atom.Ul, atom.Ol, atom.Menu:
+		r.list(n)
+	case atom.Dl:
+		r.breakLines(2)
+		r.children(n)
+		r.breakLines(2)
+	case atom.Dt:
+		r.breakLines(1)
+		r.children(n)
+		r.breakLines(1)
+	case atom.Dd:
+		r.breakLines(1)
+		r.indented("    ", func() { r.children(n) })
+		r.breakLines(1)
+	case atom.Table:
+		r.table(n)
+	case atom.A:
+		r.children(n)
+		r.link(n, "href")
+	case atom.Img:
+		if alt, ok := nodeAttr(n, "alt"); ok && strings.TrimSpace(alt) != "" {
+			r.text("[" + strings.TrimSpace(alt) + "]")
+		}
+	case atom.Input:
+		r.input(n)
+	case atom.Select:
+		r.selectValue(n)
+	case atom.Textarea:
+		r.breakLines(1)
+		r.pre++
+		r.children(n)
+		r.pre--
+		r.breakLines(1)
+	case atom.Button:
+		r.text("[")
+		r.children(n)
+		r.word("]")
+	default:
+		if isBlockElement(n) {
+			r.breakLines(1)
+			r.children(n)
+			r.breakLines(1)
+		} else {
+			r.children(n)
+		}
+	}
+}
+
+// children renders each child of the node.
+func (r *textRenderer) children(n *nethtml.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+// list renders the items of a ul, ol or menu element.
+func (r *textRenderer) list(n *nethtml.Node) {
+	r.breakLines(1)
+	num := 1
+	if start, ok := nodeAttr(n, "start"); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(start)); err == nil {
+			num = i
+		}
+	}
+	r.indented("  ", func() {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != nethtml.ElementNode || c.DataAtom != atom.Li {
+				r.render(c)
+				continue
+			}
+			if isHiddenElement(c) {
+				continue
+			}
+			marker := "* "
+			if n.DataAtom == atom.Ol {
+				if v, ok := nodeAttr(c, "value"); ok {
+					if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+						num = i
+					}
+				}
+				marker = strconv.Itoa(num) + ". "
+				num++
+			}
+			r.breakLines(1)
+			r.word(marker)
+			r.space = false
+			r.indented(strings.Repeat(" ", len(marker)), func() { r.children(c) })
+			r.breakLines(1)
+		}
+	})
+	r.breakLines(1)
+}
+
+// table lays out the cells of a table in columns.
+func (r *textRenderer) table(n *nethtml.Node) {
+	table := newTable(goquery.NewDocumentFromNode(n).Selection, r.resolve)
+	grid := append(append([][]*TableCell{}, table.HeaderRows...), table.Rows...)
+	if len(grid) == 0 {
+		return
+	}
+
+	texts := make([][]string, len(grid))
+	var widths []int
+	for i, row := range grid {
+		texts[i] = make([]string, len(row))
+		for j, cell := range row {
+			if !cell.Spanned && cell.Selection != nil {
+				cr := &textRenderer{resolve: r.resolve, links: r.links, lineStart: true}
+				cr.children(cell.Selection.Get(0))
+				texts[i][j] = collapseSpace(cr.buf.String())
+			}
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := utf8.RuneCountInString(texts[i][j]); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+
+	r.breakLines(2)
+	if caption := collapseSpace(table.Caption); caption != "" {
+		r.writeLine(caption)
+		r.breakLines(1)
+	}
+	for i, row := range texts {
+		var line strings.Builder
+		for j, text := range row {
+			if j > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(text)
+			if j < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(text)))
+			}
+		}
+		r.writeLine(line.String())
+		r.breakLines(1)
+		if i == len(table.HeaderRows)-1 {
+			var rule []string
+			for _, w := range widths {
+				rule = append(rule, strings.Repeat("-", w))
+			}
+			r.writeLine(strings.Join(rule, "  "))
+			r.breakLines(1)
+		}
+	}
+	r.breakLines(2)
+}
+
+// link adds the URL in the named attribute to the references, and writes the
+// reference number.
+func (r *textRenderer) link(n *nethtml.Node, attr string) {
+	href, ok := nodeAttr(n, attr)
+	href = strings.TrimSpace(href)
+	if !ok || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return
+	}
+	if u, err := url.Parse(href); err == nil {
+		href = r.resolve(u).String()
+	}
+	*r.links = append(*r.links, href)
+	r.word("[" + strconv.Itoa(len(*r.links)) + "]")
+}
+
+// input writes the value of a form input.
+func (r *textRenderer) input(n *nethtml.Node) {
+	t, _ := nodeAttr(n, "type")
+	val, _ := nodeAttr(n, "value")
+	_, checked := nodeAttr(n, "checked")
+	switch strings.ToLower(t) {
+	case "hidden", "file":
+		return
+	case "checkbox":
+		if checked {
+			r.text("[X]")
+		} else {
+			r.text("[ ]")
+		}
+	case "radio":
+		if checked {
+			r.text("(*)")
+		} else {
+			r.text("( )")
+		}
+	case "image":
+		if alt, ok := nodeAttr(n, "alt"); ok {
+			val = alt
+		}
+		r.text("[" + val + "]")
+	default:
+		r.text("[" + val + "]")
+	}
+}
+
+// selectValue writes the label of the selected option of a select element.
+func (r *textRenderer) selectValue(n *nethtml.Node) {
+	s := goquery.NewDocumentFromNode(n).Selection
+	opt := s.Find("option[selected]").First()
+	if opt.Length() == 0 {
+		opt = s.Find("option").First()
+	}
+	r.text("[" + collapseSpace(opt.Text()) + "]")
+}
+
+// indented renders with the given prefix added to the indentation.
+func (r *textRenderer) indented(prefix string, fn func()) {
+	old := r.indent
+	r.indent += prefix
+	fn()
+	r.indent = old
+}
+
+// text writes the text of a text node, collapsing white space unless inside
+// of a preformatted element.
+func (r *textRenderer) text(s string) {
+	if r.pre > 0 {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				r.newline()
+			}
+			if line != "" {
+				r.word(line)
+			}
+		}
+		return
+	}
+	if s == "" {
+		return
+	}
+	words := strings.Fields(s)
+	if len(words) == 0 || isSpace(s[0]) {
+		r.space = true
+	}
+	for i, w := range words {
+		if i > 0 {
+			r.space = true
+		}
+		r.word(w)
+	}
+	if len(words) > 0 && isSpace(s[len(s)-1]) {
+		r.space = true
+	}
+}
+
+// word writes the string, preceded by any pending line breaks, indentation or space.
+func (r *textRenderer) word(s string) {
+	if r.newlines > 0 {
+		if r.buf.Len() > 0 {
+			r.buf.WriteString(strings.Repeat("\n", r.newlines))
+		}
+		r.newlines = 0
+		r.lineStart = true
+	}
+	if r.lineStart {
+		r.buf.WriteString(r.indent)
+		r.lineStart = false
+	} else if r.space {
+		r.buf.WriteByte(' ')
+	}
+	r.space = false
+	r.buf.WriteString(s)
+}
+
+// writeLine writes the string as a line of its own.
+func (r *textRenderer) writeLine(s string) {
+	r.breakLines(1)
+	r.word(s)
+	r.breakLines(1)
+}
+
+// breakLines ensures at least n line breaks are written before the next text.
+func (r *textRenderer) breakLines(n int) {
+	if r.lineStart && r.newlines == 0 && r.buf.Len() > 0 {
+		// A line break was already written.
+		n--
+	}
+	if n > r.newlines {
+		r.newlines = n
+	}
+	r.space = false
+}
+
+// newline writes a single line break, which may leave a blank line.
+func (r *textRenderer) newline() {
+	if r.lineStart && r.newlines == 0 {
+		r.buf.WriteByte('\n')
+	} else {
+		r.newlines++
+	}
+	r.space = false
+}
+
+// isHiddenElement returns true for elements which are not displayed. The
+// content of noscript elements is parsed as raw markup when scripting is
+// enabled, so it's never displayed either.
+func isHiddenElement(n *nethtml.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Template, atom.Noscript, atom.Head, atom.Title, atom.Meta, atom.Link:
+		return true
+	}
+	if _, ok := nodeAttr(n, "hidden"); ok {
+		return true
+	}
+	if v, ok := nodeAttr(n, "aria-hidden"); ok && strings.EqualFold(strings.TrimSpace(v), "true") {
+		return true
+	}
+	if style, ok := nodeAttr(n, "style"); ok {
+		style = strings.ToLower(strings.Replace(style, " ", "", -1))
+		if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+			return true
+		}
+	}
+	return false
+}
+
+// blockElements are the elements which start on a new line.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Body: true,
+	atom.Caption: true, atom.Center: true, atom.Details: true, atom.Dialog: true,
+	atom.Div: true, atom.Fieldset: true, atom.Figcaption: true, atom.Footer: true,
+	atom.Form: true, atom.Header: true, atom.Hgroup: true, atom.Html: true,
+	atom.Legend: true, atom.Li: true, atom.Main: true, atom.Nav: true,
+	atom.Section: true, atom.Summary: true, atom.Tr: true,
+}
+
+// isBlockElement returns true for elements which start on a new line.
+func isBlockElement(n *nethtml.Node) bool {
+	return blockElements[n.DataAtom]
+}
+
+// isSpace returns true for the HTML white space characters.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// cleanText removes trailing space from each line, collapses runs of blank
+// lines, and ends the text with a single line break.
+func cleanText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	s = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	s = strings.Trim(s, "\n")
+	if s == "" {
+		return ""
+	}
+	return s + "\n"
+}
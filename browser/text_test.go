@@ -0,0 +1,85 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/ut"
+)
+
+var htmlText = `<!doctype html>
+<html>
+	<head>
+		<title>Surf</title>
+		<style>body { color: red; }</style>
+	</head>
+	<body>
+		<h1>Surf   Report</h1>
+		<p>Waves are <b>big</b> today.<br>Read the <a href="/report">full report</a>.</p>
+		<script>alert("hi");</script>
+		<noscript><iframe src="/tracking.html"></iframe></noscript>
+		<div hidden>Hidden text</div>
+		<span style="display: none">Invisible</span>
+		<ul>
+			<li>Board</li>
+			<li>Wax
+				<ol start="3"><li>Base</li><li>Top</li></ol>
+			</li>
+		</ul>
+		<table>
+			<tr><th>Beach</th><th>Height</th></tr>
+			<tr><td><a href="north">North</a></td><td>2m</td></tr>
+			<tr><td>South Point</td><td>1m</td></tr>
+		</table>
+		<pre>line 1
+  line 2</pre>
+		<a href="javascript:void(0)">Nowhere</a>
+	</body>
+</html>`
+
+var textExpected = `Surf Report
+
+Waves are big today.
+Read the full report[1].
+
+  * Board
+  * Wax
+      3. Base
+      4. Top
+
+Beach        Height
+-----------  ------
+North[2]     2m
+South Point  1m
+
+line 1
+  line 2
+
+Nowhere
+
+References
+
+   1. %s/report
+   2. %s/beach/north
+`
+
+func TestPlainText(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlText))
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL + "/beach/")
+	ut.AssertNil(err)
+	expected := strings.Replace(textExpected, "%s", ts.URL, -1)
+	ut.AssertEquals(expected, bow.PlainText())
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlText))
+	ut.AssertNil(err)
+	ut.AssertEquals("Waves are big today.\nRead the full report[1].\n\nReferences\n\n   1. /report\n", PlainText(doc.Find("p")))
+}
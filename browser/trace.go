@@ -0,0 +1,118 @@
+package browser
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer starts the spans which trace the operations of a browser, such as
+// opening pages, clicking, submitting forms and downloading assets. It has
+// the shape of an OpenTelemetry tracer, so one can be adapted without Surf
+// depending on OpenTelemetry.
+//
+// Spans are tagged with the "url.full", "http.request.method",
+// "http.response.status_code" and "http.redirect_count" attributes of the
+// requests made during the operation.
+type Tracer interface {
+	// Start starts a span with the name as a child of the span in ctx, and
+	// returns a context holding the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+
+	// Inject adds the headers which propagate the span in ctx, such as
+	// traceparent, to the headers of a request.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is an operation traced by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute of the span.
+	SetAttribute(key string, value interface{})
+
+	// RecordError records an error which made the operation fail.
+	RecordError(err error)
+
+	// End ends the span.
+	End()
+}
+
+// SetTracer sets the tracer which traces the operations of the browser.
+// Passing nil stops tracing.
+func (bow *Browser) SetTracer(t Tracer) {
+	bow.tracer = t
+}
+
+// SetTraceContext sets the context which holds the parent span of the spans
+// started by the browser.
+func (bow *Browser) SetTraceContext(ctx context.Context) {
+	bow.traceParent = ctx
+}
+
+// traceOperation starts a span for an operation of the browser, which becomes
+// the parent of the spans and requests of the operation until it ends. The
+// returned function ends the span, recording the error when it's not nil.
+func (bow *Browser) traceOperation(name string, attrs map[string]interface{}) func(err error) {
+	prevCtx, prevSpan := bow.traceCtx, bow.span
+	ctx, span, end := startSpan(bow.tracer, bow.traceContext(), name, attrs)
+	if span == nil {
+		return end
+	}
+	bow.traceCtx, bow.span = ctx, span
+	return func(err error) {
+		end(err)
+		bow.traceCtx, bow.span = prevCtx, prevSpan
+	}
+}
+
+// traceContext returns the context holding the span of the running operation.
+func (bow *Browser) traceContext() context.Context {
+	if bow.traceCtx != nil {
+		return bow.traceCtx
+	}
+	if bow.traceParent != nil {
+		return bow.traceParent
+	}
+	return context.Background()
+}
+
+// injectTrace adds the headers which propagate the span of the running
+// operation to the request.
+func (bow *Browser) injectTrace(req *http.Request) {
+	if bow.tracer != nil {
+		bow.tracer.Inject(bow.traceContext(), req.Header)
+	}
+}
+
+// traceResponse sets the attributes of the response on the span of the
+// running operation.
+func (bow *Browser) traceResponse(resp *http.Response) {
+	if bow.span != nil {
+		setResponseAttributes(bow.span, resp)
+	}
+}
+
+// startSpan starts a span with the tracer and sets its attributes. The span
+// is nil when the tracer is nil. The returned function ends the span,
+// recording the error when it's not nil.
+func startSpan(t Tracer, ctx context.Context, name string, attrs map[string]interface{}) (context.Context, Span, func(error)) {
+	if t == nil {
+		return ctx, nil, func(error) {}
+	}
+	ctx, span := t.Start(ctx, name)
+	for k, v := range attrs {
+		span.SetAttribute(k, v)
+	}
+	return ctx, span, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
+
+// setResponseAttributes sets the attributes of the response on the span.
+func setResponseAttributes(span Span, resp *http.Response) {
+	span.SetAttribute("url.full", resp.Request.URL.String())
+	span.SetAttribute("http.request.method", resp.Request.Method)
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	span.SetAttribute("http.redirect_count", len(redirectChain(resp)))
+}
@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/headzoo/ut"
+)
+
+type spanKey struct{}
+
+type recordingSpan struct {
+	name   string
+	parent *recordingSpan
+	attrs  map[string]interface{}
+	errors []error
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *recordingSpan) RecordError(err error)                      { s.errors = append(s.errors, err) }
+func (s *recordingSpan) End()                                       { s.ended = true }
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordingSpan)
+	span := &recordingSpan{name: name, parent: parent, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(spanKey{}).(*recordingSpan); ok {
+		header.Set("Traceparent", span.name)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	ut.Run(t)
+	var traceparents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/page":
+			fmt.Fprint(w, `<html><body><a href="/next">Next</a><img src="/asset.txt">
+<form method="post" action="/submit"><input name="q" value="surf"><button>Go</button></form></body></html>`)
+		case "/next":
+			fmt.Fprint(w, `<html><head><title>Next</title></head></html>`)
+		case "/submit":
+			http.Error(w, "Broken", 500)
+		case "/asset.txt":
+			fmt.Fprint(w, "asset")
+		}
+	}))
+	defer ts.Close()
+
+	tracer := &recordingTracer{}
+	parentCtx, parent := tracer.Start(context.Background(), "parent")
+	bow := newBrowser()
+	bow.SetAttributes(AttributeMap{FollowRedirects: true})
+	bow.SetTracer(tracer)
+	bow.SetTraceContext(parentCtx)
+
+	ut.AssertNil(bow.Open(ts.URL))
+	open := tracer.spans[1]
+	ut.AssertEquals("surf.Open", open.name)
+	ut.AssertTrue(open.parent == parent)
+	ut.AssertTrue(open.ended)
+	ut.AssertEquals(ts.URL+"/page", open.attrs["url.full"])
+	ut.AssertEquals("GET", open.attrs["http.request.method"])
+	ut.AssertEquals(200, open.attrs["http.response.status_code"])
+	ut.AssertEquals(1, open.attrs["http.redirect_count"])
+	ut.AssertEquals([]string{"surf.Open", "surf.Open"}, traceparents)
+
+	ut.AssertNil(bow.Click("a"))
+	click := tracer.spans[2]
+	ut.AssertEquals("surf.Click", click.name)
+	ut.AssertEquals("a", click.attrs["surf.expr"])
+	ut.AssertEquals(ts.URL+"/next", click.attrs["url.full"])
+	ut.AssertEquals("surf.Click", traceparents[2])
+
+	ut.AssertTrue(bow.Back())
+	bow.SetAttribute(StatusErrors, true)
+	ut.AssertNotNil(bow.Click("button"))
+	click, submit := tracer.spans[3], tracer.spans[4]
+	ut.AssertEquals("surf.Submit", submit.name)
+	ut.AssertTrue(submit.parent == click)
+	ut.AssertEquals("POST", submit.attrs["http.request.method"])
+	ut.AssertEquals(500, submit.attrs["http.response.status_code"])
+	ut.AssertEquals(1, len(submit.errors))
+	ut.AssertEquals(1, len(click.errors))
+	ut.AssertTrue(submit.ended && click.ended)
+	ut.AssertEquals("surf.Submit", traceparents[3])
+
+	u, _ := url.Parse(ts.URL + "/asset.txt")
+	var out bytes.Buffer
+	_, err := bow.DownloadAsset(NewImageAsset(u, "", "", ""), &out)
+	ut.AssertNil(err)
+	ut.AssertEquals("asset", out.String())
+	download := tracer.spans[5]
+	ut.AssertEquals("surf.DownloadAsset", download.name)
+	ut.AssertEquals(200, download.attrs["http.response.status_code"])
+	ut.AssertEquals("surf.DownloadAsset", traceparents[4])
+	ut.AssertTrue(download.parent == parent)
+
+	// Assets found by the browser are downloaded with its tracer.
+	ut.AssertTrue(bow.Back())
+	out.Reset()
+	_, err = bow.Images()[0].Download(&out)
+	ut.AssertNil(err)
+	ut.AssertEquals("asset", out.String())
+	download = tracer.spans[len(tracer.spans)-1]
+	ut.AssertEquals("surf.DownloadAsset", download.name)
+	ut.AssertTrue(download.ended)
+	ut.AssertEquals("surf.DownloadAsset", traceparents[len(traceparents)-1])
+}
@@ -0,0 +1,284 @@
+package browser
+
+import (
+	"encoding"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/surf/errors"
+)
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	urlType         = reflect.TypeOf(url.URL{})
+	unmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshal copies data from the selection into the struct pointed at by v.
+//
+// Struct fields are mapped onto elements using tags. The find tag holds a CSS
+// selector which is matched against the descendants of the selection, and the
+// attr tag names an attribute to read instead of the element text. Fields with
+// an attr tag and no find tag read the attribute of the selection itself.
+// Fields without either tag are skipped.
+//
+//	type Product struct {
+//		Name   string    `find:"h1.name" required:"true"`
+//		Price  float64   `find:".price"`
+//		Link   url.URL   `find:"a.details" attr:"href"`
+//		Added  time.Time `find:".added" layout:"2006-01-02"`
+//		Tags   []string  `find:"ul.tags li"`
+//		Seller struct {
+//			Name string `find:".name"`
+//		} `find:".seller"`
+//	}
+//
+// The text of the first matching element is used for single values, while
+// slices hold a value for every matching element. Nested structs are
+// unmarshaled from the first matching element, and slices of structs from
+// every matching element. Element text has its leading and trailing space
+// removed.
+//
+// Values are converted to the type of the field. Strings, bools, integers,
+// floats, url.URL, time.Time and types implementing encoding.TextUnmarshaler
+// are supported. Times are parsed with the layout tag, which defaults to
+// time.RFC3339.
+//
+// Fields tagged `required:"true"` return an ElementNotFound error when no
+// element matches, and an AttributeNotFound error when the element does not
+// have the attribute. Other missing fields are left unchanged.
+func Unmarshal(sel *goquery.Selection, v interface{}) error {
+	u := &unmarshaler{resolve: func(u *url.URL) *url.URL { return u }}
+	return u.unmarshal(sel, v)
+}
+
+// Unmarshal copies data from the selection into the struct pointed at by v.
+// The whole page is used when the selection is nil. It works just like the
+// Unmarshal function, except that URLs are resolved with ResolveUrl.
+func (bow *Browser) Unmarshal(sel *goquery.Selection, v interface{}) error {
+	if sel == nil {
+		sel = bow.Dom()
+	}
+	u := &unmarshaler{resolve: bow.ResolveUrl}
+	return u.unmarshal(sel, v)
+}
+
+// unmarshaler copies data from a selection into a struct.
+type unmarshaler struct {
+	// resolve creates an absolute URL from a possibly relative URL.
+	resolve func(u *url.URL) *url.URL
+}
+
+// fieldTags holds the tags of a struct field.
+type fieldTags struct {
+	path     string
+	attr     string
+	hasAttr  bool
+	layout   string
+	required bool
+}
+
+// unmarshal checks the value v is a pointer to a struct, and fills the struct.
+func (u *unmarshaler) unmarshal(sel *goquery.Selection, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("Unmarshal requires a non-nil pointer, got %T.", v)
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return errors.New("Unmarshal requires a pointer to a struct, got %T.", v)
+	}
+	return u.unmarshalStruct(sel, rv, rv.Type().Name())
+}
+
+// unmarshalStruct fills the fields of the struct v from the selection.
+func (u *unmarshaler) unmarshalStruct(sel *goquery.Selection, v reflect.Value, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		find, hasFind := sf.Tag.Lookup("find")
+		attr, hasAttr := sf.Tag.Lookup("attr")
+		if !hasFind && !hasAttr {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := u.unmarshalStruct(sel, fv, path); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		tags := fieldTags{
+			path:     path + "." + sf.Name,
+			attr:     attr,
+			hasAttr:  hasAttr,
+			layout:   sf.Tag.Get("layout"),
+			required: sf.Tag.Get("required") == "true",
+		}
+		target := sel
+		if find != "" {
+			target = sel.Find(find)
+		}
+		if err := u.unmarshalValue(target, fv, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalValue sets the value v from the selection.
+func (u *unmarshaler) unmarshalValue(sel *goquery.Selection, v reflect.Value, tags fieldTags) error {
+	if sel.Length() == 0 {
+		if tags.required {
+			return errors.NewElementNotFound(
+				"No element found for the required field %s.", tags.path).With(errors.Fields{Field: tags.path})
+		}
+		return nil
+	}
+
+	switch {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		slice := reflect.MakeSlice(v.Type(), 0, sel.Length())
+		for i := range sel.Nodes {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			ok, err := u.unmarshalOne(sel.Eq(i), elem, tags)
+			if err != nil {
+				return err
+			}
+			if ok {
+				slice = reflect.Append(slice, elem)
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	_, err := u.unmarshalOne(sel.First(), v, tags)
+	return err
+}
+
+// unmarshalOne sets the value v from a single element. Returns false when
+// the element does not have the attribute named by the attr tag.
+func (u *unmarshaler) unmarshalOne(sel *goquery.Selection, v reflect.Value, tags fieldTags) (bool, error) {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		ok, err := u.unmarshalOne(sel, elem.Elem(), tags)
+		if ok && err == nil {
+			v.Set(elem)
+		}
+		return ok, err
+	}
+	if v.Kind() == reflect.Struct && !isScalarStruct(v) {
+		return true, u.unmarshalStruct(sel, v, tags.path)
+	}
+
+	var text string
+	if tags.hasAttr {
+		val, ok := sel.Attr(tags.attr)
+		if !ok {
+			if tags.required {
+				return false, errors.NewAttributeNotFound(
+					"Attribute '%s' not found for the required field %s.", tags.attr, tags.path).
+					With(errors.Fields{Field: tags.attr})
+			}
+			return false, nil
+		}
+		text = strings.TrimSpace(val)
+	} else {
+		text = strings.TrimSpace(sel.Text())
+	}
+	return true, u.convert(text, v, tags)
+}
+
+// convert parses the text into the value v.
+func (u *unmarshaler) convert(text string, v reflect.Value, tags fieldTags) error {
+	switch v.Type() {
+	case timeType:
+		layout := tags.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, text)
+		if err != nil {
+			return conversionError(err, text, tags)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case urlType:
+		pu, err := url.Parse(text)
+		if err != nil {
+			return conversionError(err, text, tags)
+		}
+		v.Set(reflect.ValueOf(*u.resolve(pu)))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+		return conversionError(err, text, tags)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return conversionError(err, text, tags)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return conversionError(err, text, tags)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return conversionError(err, text, tags)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return conversionError(err, text, tags)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return unsupportedType(v, tags)
+		}
+		v.SetBytes([]byte(text))
+	default:
+		return unsupportedType(v, tags)
+	}
+	return nil
+}
+
+// unsupportedType returns the error for a field whose type text can't be
+// converted to, such as a map or a slice of slices.
+func unsupportedType(v reflect.Value, tags fieldTags) error {
+	return errors.New("Cannot unmarshal into the field %s of type %s.", tags.path, v.Type()).
+		With(errors.Fields{Field: tags.path})
+}
+
+// conversionError wraps an error that occurred while converting text for a field.
+func conversionError(err error, text string, tags fieldTags) error {
+	if err == nil {
+		return nil
+	}
+	return errors.New("Cannot convert '%s' for the field %s: %s", text, tags.path, err).
+		With(errors.Fields{Field: tags.path, Err: err})
+}
+
+// isScalarStruct returns true for struct types which are unmarshaled from text.
+func isScalarStruct(v reflect.Value) bool {
+	return v.Type() == timeType || v.Type() == urlType ||
+		(v.CanAddr() && v.Addr().Type().Implements(unmarshalerType))
+}
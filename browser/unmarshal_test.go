@@ -0,0 +1,149 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	surferrors "github.com/headzoo/surf/errors"
+	"github.com/headzoo/ut"
+)
+
+var htmlProducts = `<!doctype html>
+<html>
+	<body>
+		<div class="product" data-sku="A1">
+			<h2> Surf Board </h2>
+			<span class="price">199.95</span>
+			<span class="stock">12</span>
+			<span class="added">2017-03-18</span>
+			<a class="details" href="/products/a1">Details</a>
+			<ul class="tags"><li>water</li><li>sport</li></ul>
+			<div class="seller"><span class="name">Headzoo</span></div>
+		</div>
+		<div class="product" data-sku="B2">
+			<h2>Wet Suit</h2>
+			<span class="price">89.50</span>
+			<span class="stock">0</span>
+			<span class="added">2017-04-01</span>
+			<a class="details">No link</a>
+		</div>
+	</body>
+</html>`
+
+type unmarshalSeller struct {
+	Name string `find:".name"`
+}
+
+type unmarshalProduct struct {
+	SKU    string           `attr:"data-sku"`
+	Name   string           `find:"h2" required:"true"`
+	Price  float64          `find:".price"`
+	Stock  int              `find:".stock"`
+	Added  time.Time        `find:".added" layout:"2006-01-02"`
+	Link   *url.URL         `find:"a.details" attr:"href"`
+	Tags   []string         `find:"ul.tags li"`
+	Seller *unmarshalSeller `find:".seller"`
+	Skip   string
+}
+
+type unmarshalPage struct {
+	Products []unmarshalProduct `find:".product"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlProducts))
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL + "/catalog/")
+	ut.AssertNil(err)
+
+	var page unmarshalPage
+	err = bow.Unmarshal(nil, &page)
+	ut.AssertNil(err)
+	ut.AssertEquals(2, len(page.Products))
+
+	p := page.Products[0]
+	ut.AssertEquals("A1", p.SKU)
+	ut.AssertEquals("Surf Board", p.Name)
+	ut.AssertEquals(199.95, p.Price)
+	ut.AssertEquals(12, p.Stock)
+	ut.AssertEquals(time.Date(2017, 3, 18, 0, 0, 0, 0, time.UTC), p.Added)
+	ut.AssertEquals(ts.URL+"/products/a1", p.Link.String())
+	ut.AssertEquals([]string{"water", "sport"}, p.Tags)
+	ut.AssertEquals("Headzoo", p.Seller.Name)
+
+	p = page.Products[1]
+	ut.AssertEquals("Wet Suit", p.Name)
+	ut.AssertEquals(0, p.Stock)
+	ut.AssertTrue(p.Link == nil)
+	ut.AssertTrue(p.Tags == nil)
+	ut.AssertTrue(p.Seller == nil)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	ut.Run(t)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlProducts))
+	ut.AssertNil(err)
+
+	var missing struct {
+		Title string `find:"h1" required:"true"`
+	}
+	err = Unmarshal(doc.Selection, &missing)
+	_, ok := err.(surferrors.ElementNotFound)
+	ut.AssertTrue(ok)
+
+	var attr struct {
+		Links []string `find:"a.details" attr:"href" required:"true"`
+	}
+	err = Unmarshal(doc.Selection, &attr)
+	_, ok = err.(surferrors.AttributeNotFound)
+	ut.AssertTrue(ok)
+
+	var invalid struct {
+		Price int `find:".price"`
+	}
+	err = Unmarshal(doc.Selection, &invalid)
+	ut.AssertNotNil(err)
+
+	var relative struct {
+		Link url.URL `find:"a.details" attr:"href"`
+	}
+	err = Unmarshal(doc.Selection, &relative)
+	ut.AssertNil(err)
+	ut.AssertEquals("/products/a1", relative.Link.String())
+
+	err = Unmarshal(doc.Selection, relative)
+	ut.AssertNotNil(err)
+
+	var raw struct {
+		Name []byte `find:"h2"`
+	}
+	err = Unmarshal(doc.Selection, &raw)
+	ut.AssertNil(err)
+	ut.AssertEquals("Surf Board", string(raw.Name))
+
+	var nested struct {
+		Tags [][]string `find:"ul.tags li"`
+	}
+	err = Unmarshal(doc.Selection, &nested)
+	serr, ok := err.(surferrors.Error)
+	ut.AssertTrue(ok)
+	ut.AssertEquals(".Tags", serr.Field)
+
+	var mapped struct {
+		Tags map[string]string `find:"ul.tags li"`
+	}
+	err = Unmarshal(doc.Selection, &mapped)
+	serr, ok = err.(surferrors.Error)
+	ut.AssertTrue(ok)
+	ut.AssertEquals(".Tags", serr.Field)
+}
@@ -0,0 +1,147 @@
+package browser
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// FormValue is a single name/value pair in a form data set.
+type FormValue struct {
+	Name  string
+	Value string
+}
+
+// FormValues is an ordered form data set.
+//
+// Unlike url.Values the pairs are kept in the order they were added, which
+// allows forms to be submitted with their fields in document order the same
+// way a web browser submits them.
+type FormValues []FormValue
+
+// NewFormValues creates and returns a FormValues type from the given url.Values.
+// The fields are sorted by name, the same as url.Values.Encode() sorts them.
+func NewFormValues(v url.Values) FormValues {
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fv := make(FormValues, 0, len(v))
+	for _, name := range names {
+		for _, val := range v[name] {
+			fv = append(fv, FormValue{Name: name, Value: val})
+		}
+	}
+	return fv
+}
+
+// Get returns the first value associated with the given name, or an empty
+// string when there are no values.
+func (fv FormValues) Get(name string) string {
+	for _, v := range fv {
+		if v.Name == name {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// GetAll returns every value associated with the given name, in order.
+func (fv FormValues) GetAll(name string) []string {
+	var vals []string
+	for _, v := range fv {
+		if v.Name == name {
+			vals = append(vals, v.Value)
+		}
+	}
+	return vals
+}
+
+// Has returns a boolean indicating whether the given name has any values.
+func (fv FormValues) Has(name string) bool {
+	for _, v := range fv {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Add appends the value to the end of the data set.
+func (fv *FormValues) Add(name, value string) {
+	*fv = append(*fv, FormValue{Name: name, Value: value})
+}
+
+// Set replaces the values associated with the given name with the single
+// value. The value keeps the position of the first existing value, or is
+// appended to the end of the data set when the name has no values.
+func (fv *FormValues) Set(name, value string) {
+	found := false
+	vals := (*fv)[:0]
+	for _, v := range *fv {
+		if v.Name != name {
+			vals = append(vals, v)
+		} else if !found {
+			vals = append(vals, FormValue{Name: name, Value: value})
+			found = true
+		}
+	}
+	*fv = vals
+	if !found {
+		fv.Add(name, value)
+	}
+}
+
+// Del removes every value associated with the given name.
+func (fv *FormValues) Del(name string) {
+	vals := (*fv)[:0]
+	for _, v := range *fv {
+		if v.Name != name {
+			vals = append(vals, v)
+		}
+	}
+	*fv = vals
+}
+
+// DelValue removes every pair whose name and value match.
+func (fv *FormValues) DelValue(name, value string) {
+	vals := (*fv)[:0]
+	for _, v := range *fv {
+		if v.Name != name || v.Value != value {
+			vals = append(vals, v)
+		}
+	}
+	*fv = vals
+}
+
+// Encode encodes the values into "URL encoded" form, eg "bar=baz&foo=quux",
+// keeping the order of the data set.
+func (fv FormValues) Encode() string {
+	var buf strings.Builder
+	for i, v := range fv {
+		if i > 0 {
+			buf.WriteByte('&')
+		}
+		buf.WriteString(url.QueryEscape(v.Name))
+		buf.WriteByte('=')
+		buf.WriteString(url.QueryEscape(v.Value))
+	}
+	return buf.String()
+}
+
+// UrlValues returns the data set as a url.Values type, which loses the
+// ordering between different names.
+func (fv FormValues) UrlValues() url.Values {
+	vals := make(url.Values, len(fv))
+	for _, v := range fv {
+		vals.Add(v.Name, v.Value)
+	}
+	return vals
+}
+
+// Copy returns a copy of the data set.
+func (fv FormValues) Copy() FormValues {
+	return append(FormValues(nil), fv...)
+}
@@ -0,0 +1,174 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/headzoo/surf/errors"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// XMLDocument is a parsed XML document, such as an RSS feed or a SOAP
+// response, which is queried with XPath.
+//
+// Queries are namespace aware. Names without a prefix match elements by their
+// local name in any namespace, and prefixed names match elements in the
+// namespace the prefix is bound to. The prefixes declared in the document are
+// bound automatically, and SetNamespace binds others.
+type XMLDocument struct {
+	// Root is the document node.
+	Root *xmlquery.Node
+
+	// namespaces maps the prefixes used in queries to namespace URIs.
+	namespaces map[string]string
+}
+
+// NewXMLDocument parses the XML document read from r. The encoding declared
+// by the document is converted to UTF-8.
+func NewXMLDocument(r io.Reader) (*XMLDocument, error) {
+	root, err := xmlquery.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := &XMLDocument{Root: root, namespaces: make(map[string]string)}
+	var walk func(*xmlquery.Node)
+	walk = func(n *xmlquery.Node) {
+		for _, a := range n.Attr {
+			if a.Name.Space == "xmlns" {
+				if _, ok := doc.namespaces[a.Name.Local]; !ok {
+					doc.namespaces[a.Name.Local] = a.Value
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return doc, nil
+}
+
+// SetNamespace binds the prefix to the namespace URI in queries, replacing
+// the binding declared by the document.
+func (d *XMLDocument) SetNamespace(prefix, uri string) {
+	d.namespaces[prefix] = uri
+}
+
+// Find returns the nodes matching the XPath expression.
+func (d *XMLDocument) Find(expr string) ([]*xmlquery.Node, error) {
+	compiled, err := xpath.CompileWithNS(expr, d.namespaces)
+	if err != nil {
+		return nil, errors.New("Invalid XPath expression '%s': %s", expr, err).
+			With(errors.Fields{Expr: expr, Err: err})
+	}
+	return xmlquery.QuerySelectorAll(d.Root, compiled), nil
+}
+
+// FindOne returns the first node matching the XPath expression. An
+// ElementNotFound error is returned when nothing matches.
+func (d *XMLDocument) FindOne(expr string) (*xmlquery.Node, error) {
+	nodes, err := d.Find(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, errors.NewElementNotFound(
+			"Element not found matching expr '%s'.", expr).With(errors.Fields{Expr: expr})
+	}
+	return nodes[0], nil
+}
+
+// XML parses the page as an XML document. An UnsupportedMediaType error is
+// returned when the media type of the page isn't XML, such as "text/xml",
+// "application/xml", or one ending in "+xml".
+func (bow *Browser) XML() (*XMLDocument, error) {
+	if !isXMLMediaType(bow.MediaType()) {
+		return nil, errors.NewUnsupportedMediaType(
+			"Cannot parse a '%s' document as XML.", bow.MediaType())
+	}
+	return NewXMLDocument(bytes.NewReader(bow.state.Body))
+}
+
+// JSON decodes the page into the value pointed to by v, the same way as
+// json.Unmarshal. An UnsupportedMediaType error is returned when the media
+// type of the page isn't JSON, such as "application/json" or one ending in
+// "+json".
+func (bow *Browser) JSON(v interface{}) error {
+	if !isJSONMediaType(bow.MediaType()) {
+		return errors.NewUnsupportedMediaType(
+			"Cannot decode a '%s' document as JSON.", bow.MediaType())
+	}
+	return json.Unmarshal(bow.state.Body, v)
+}
+
+// MediaType returns the media type of the page, such as "text/html" or
+// "application/json", without any parameters.
+func (bow *Browser) MediaType() string {
+	return bow.state.MediaType
+}
+
+// Document returns the parsed HTML document. An UnsupportedMediaType error is
+// returned when the page isn't HTML, in which case Dom, Find and Forms find
+// nothing, and Query and QueryForms return the same error.
+func (bow *Browser) Document() (*goquery.Document, error) {
+	if err := bow.requireHTML("find elements in"); err != nil {
+		return nil, err
+	}
+	return bow.state.Dom, nil
+}
+
+// requireHTML returns an UnsupportedMediaType error when the page isn't HTML.
+// The action describes what can't be done, eg "find forms in".
+func (bow *Browser) requireHTML(action string) error {
+	if mt := bow.MediaType(); mt != "" && !isHTMLMediaType(mt) {
+		return errors.NewUnsupportedMediaType(
+			"Cannot %s a '%s' document.", action, mt)
+	}
+	return nil
+}
+
+// responseMediaType returns the media type of the response. The type is
+// sniffed from the body when the response doesn't have a Content-Type header.
+func responseMediaType(resp *http.Response, body []byte) string {
+	ct := resp.Header.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(body)
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+	}
+	return mt
+}
+
+// isHTMLMediaType returns true for the media types parsed as HTML. An empty
+// type is parsed as HTML too, the same as it always has been, and responses
+// without a Content-Type header are parsed as HTML when their body is sniffed
+// as HTML.
+func isHTMLMediaType(mt string) bool {
+	return mt == "" || mt == "text/html" || mt == "application/xhtml+xml"
+}
+
+// isXMLMediaType returns true for the XML media types.
+func isXMLMediaType(mt string) bool {
+	return mt == "text/xml" || mt == "application/xml" || strings.HasSuffix(mt, "+xml")
+}
+
+// isJSONMediaType returns true for the JSON media types.
+func isJSONMediaType(mt string) bool {
+	return mt == "application/json" || mt == "text/json" || strings.HasSuffix(mt, "+json")
+}
+
+// emptyDocument returns a document without any elements, which is used as the
+// DOM of pages which aren't HTML.
+func emptyDocument() *goquery.Document {
+	return goquery.NewDocumentFromNode(&nethtml.Node{Type: nethtml.DocumentNode})
+}
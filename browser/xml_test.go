@@ -0,0 +1,134 @@
+package browser
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/headzoo/surf/errors"
+	"github.com/headzoo/ut"
+)
+
+var xmlFeed = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title>Surf News</title>
+	<entry>
+		<title>Caf` + "\xe9" + ` Waves</title>
+		<media:thumbnail url="/thumb.jpg" />
+	</entry>
+	<entry>
+		<title>Big Swell</title>
+	</entry>
+</feed>`
+
+func TestBrowserMediaTypes(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"name": "Surf", "tags": ["go", "scraping"]}`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`<form></form>`))
+		case "/feed":
+			w.Header().Set("Content-Type", "application/atom+xml")
+			w.Write([]byte(xmlFeed))
+		default:
+			w.Write([]byte(`<html><body><form></form></body></html>`))
+		}
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL + "/json")
+	ut.AssertNil(err)
+	ut.AssertEquals("application/json", bow.MediaType())
+	ut.AssertEquals("application/json", bow.State().MediaType)
+	var data struct {
+		Name string
+		Tags []string
+	}
+	ut.AssertNil(bow.JSON(&data))
+	ut.AssertEquals("Surf", data.Name)
+	ut.AssertEquals([]string{"go", "scraping"}, data.Tags)
+
+	ut.AssertEquals(0, bow.Find("*").Length())
+	ut.AssertEquals(0, len(bow.Forms()))
+	_, err = bow.Form("form")
+	_, ok := err.(errors.UnsupportedMediaType)
+	ut.AssertTrue(ok)
+	_, err = bow.Query("*")
+	_, ok = err.(errors.UnsupportedMediaType)
+	ut.AssertTrue(ok)
+	_, err = bow.QueryForms()
+	_, ok = err.(errors.UnsupportedMediaType)
+	ut.AssertTrue(ok)
+	_, err = bow.Document()
+	ut.AssertNotNil(err)
+	err = bow.Click("a")
+	ut.AssertNotNil(err)
+	_, err = bow.XML()
+	ut.AssertNotNil(err)
+
+	err = bow.Open(ts.URL + "/feed")
+	ut.AssertNil(err)
+	doc, err := bow.XML()
+	ut.AssertNil(err)
+	titles, err := doc.Find("//entry/title")
+	ut.AssertNil(err)
+	ut.AssertEquals(2, len(titles))
+	ut.AssertEquals("Café Waves", titles[0].InnerText())
+	thumb, err := doc.FindOne("//media:thumbnail/@url")
+	ut.AssertNil(err)
+	ut.AssertEquals("/thumb.jpg", thumb.InnerText())
+
+	doc.SetNamespace("atom", "http://www.w3.org/2005/Atom")
+	title, err := doc.FindOne("/atom:feed/atom:title")
+	ut.AssertNil(err)
+	ut.AssertEquals("Surf News", title.InnerText())
+	doc.SetNamespace("atom", "urn:other")
+	_, err = doc.FindOne("/atom:feed/atom:title")
+	ut.AssertNotNil(err)
+	_, err = doc.Find("//[")
+	ut.AssertNotNil(err)
+	ut.AssertNotNil(bow.JSON(&data))
+
+	err = bow.Open(ts.URL)
+	ut.AssertNil(err)
+	ut.AssertEquals("text/html", bow.MediaType())
+	_, err = bow.Document()
+	ut.AssertNil(err)
+	ut.AssertEquals(1, bow.Find("form").Length())
+	sel, err := bow.Query("form")
+	ut.AssertNil(err)
+	ut.AssertEquals(1, sel.Length())
+	forms, err := bow.QueryForms()
+	ut.AssertNil(err)
+	ut.AssertEquals(1, len(forms))
+	ut.AssertNotNil(forms[0])
+
+	// Text types other than HTML aren't parsed.
+	err = bow.Open(ts.URL + "/text")
+	ut.AssertNil(err)
+	ut.AssertEquals("text/plain", bow.MediaType())
+	ut.AssertEquals(0, len(bow.Forms()))
+	ut.AssertEquals("<form></form>", bow.Body())
+
+	// The body of the page is restored with the page by Back.
+	ut.AssertTrue(bow.Back())
+	ut.AssertEquals("text/html", bow.MediaType())
+	ut.AssertTrue(bow.Back())
+	_, err = bow.XML()
+	ut.AssertNil(err)
+	ut.AssertTrue(bow.Back())
+	ut.AssertEquals("application/json", bow.MediaType())
+	data.Name = ""
+	ut.AssertNil(bow.JSON(&data))
+	ut.AssertEquals("Surf", data.Name)
+	var out bytes.Buffer
+	_, err = bow.Download(&out)
+	ut.AssertNil(err)
+	ut.AssertEquals(`{"name": "Surf", "tags": ["go", "scraping"]}`, out.String())
+}
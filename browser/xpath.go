@@ -0,0 +1,81 @@
+package browser
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	"github.com/headzoo/surf/errors"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// XPath returns the elements matching the XPath expression, which is
+// evaluated with each element of the selection as the context node.
+//
+// XPath can address elements CSS selectors can't, such as elements containing
+// some text, eg `//a[contains(text(), "Next")]`, or the ancestors and
+// following siblings of an element. Expressions which select text nodes
+// return the elements containing the text, and attribute nodes are skipped.
+func XPath(sel *goquery.Selection, expr string) (*goquery.Selection, error) {
+	var found []*nethtml.Node
+	seen := make(map[*nethtml.Node]bool)
+	for _, n := range sel.Nodes {
+		nodes, err := htmlquery.QueryAll(n, expr)
+		if err != nil {
+			return nil, errors.New("Invalid XPath expression '%s': %s", expr, err).
+				With(errors.Fields{Expr: expr, Err: err})
+		}
+		for _, node := range nodes {
+			if node.Type == nethtml.TextNode {
+				node = node.Parent
+			}
+			if node == nil || node.Type != nethtml.ElementNode || node.Parent == nil || seen[node] {
+				continue
+			}
+			seen[node] = true
+			found = append(found, node)
+		}
+	}
+	if len(found) == 0 {
+		return sel.Slice(0, 0), nil
+	}
+	// The elements may be anywhere in the document, such as the ancestors of
+	// the selection, so they're found from the root of the document.
+	root := found[0]
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return goquery.NewDocumentFromNode(root).FindNodes(found...), nil
+}
+
+// FindXPath returns the elements in the page matching the XPath expression.
+// Nothing is found in pages which aren't HTML.
+func (bow *Browser) FindXPath(expr string) (*goquery.Selection, error) {
+	return XPath(bow.Dom(), expr)
+}
+
+// ClickXPath works just like Click, but the element is matched by an XPath
+// expression.
+func (bow *Browser) ClickXPath(expr string) error {
+	defer bow.lockNavigation()()
+	if err := bow.requireHTML("click elements in"); err != nil {
+		return err
+	}
+	sel, err := bow.FindXPath(expr)
+	if err != nil {
+		return err
+	}
+	return bow.click(sel, expr)
+}
+
+// FormXPath works just like Form, but the form is matched by an XPath
+// expression.
+func (bow *Browser) FormXPath(expr string) (Submittable, error) {
+	if err := bow.requireHTML("find forms in"); err != nil {
+		return nil, err
+	}
+	sel, err := bow.FindXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return bow.form(sel, expr)
+}
@@ -0,0 +1,87 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/headzoo/ut"
+)
+
+var htmlXPath = `<!doctype html>
+<html>
+	<body>
+		<div class="results">
+			<h2>Results</h2>
+			<ul>
+				<li><span>Surf</span> <a href="/surf">More</a></li>
+				<li><span>Wax</span> <a href="/wax">More</a></li>
+			</ul>
+			<a href="/page/2">Next page</a>
+		</div>
+		<form action="/search"><label>Search</label><input name="q"></form>
+	</body>
+</html>`
+
+func TestBrowserXPath(t *testing.T) {
+	ut.Run(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(htmlXPath))
+			return
+		}
+		w.Write([]byte("<html><head><title>" + r.URL.Path + "</title></head></html>"))
+	}))
+	defer ts.Close()
+
+	bow := newBrowser()
+	err := bow.Open(ts.URL)
+	ut.AssertNil(err)
+
+	sel, err := bow.FindXPath(`//span[text()="Wax"]/following-sibling::a`)
+	ut.AssertNil(err)
+	ut.AssertEquals(1, sel.Length())
+	ut.AssertEquals("/wax", sel.AttrOr("href", ""))
+	ut.AssertEquals("Results", sel.Closest(".results").Find("h2").Text())
+
+	sel, err = bow.FindXPath(`//span/text()`)
+	ut.AssertNil(err)
+	ut.AssertEquals(2, sel.Length())
+	ut.AssertTrue(sel.Is("span"))
+
+	sel, err = bow.FindXPath(`//nothing`)
+	ut.AssertNil(err)
+	ut.AssertEquals(0, sel.Length())
+	_, err = bow.FindXPath(`//a[`)
+	ut.AssertNotNil(err)
+
+	f, err := bow.FormXPath(`//label[text()="Search"]/ancestor::form`)
+	ut.AssertNil(err)
+	ut.AssertEquals(ts.URL+"/search", f.Action())
+	_, err = bow.FormXPath(`//ul`)
+	ut.AssertNotNil(err)
+
+	err = bow.ClickXPath(`//a[contains(text(), "Next")]`)
+	ut.AssertNil(err)
+	ut.AssertEquals("/page/2", bow.Title())
+	err = bow.ClickXPath(`//a`)
+	ut.AssertNotNil(err)
+}
+
+func TestXPath(t *testing.T) {
+	ut.Run(t)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlXPath))
+	ut.AssertNil(err)
+
+	sel, err := XPath(doc.Find("li"), "./a")
+	ut.AssertNil(err)
+	ut.AssertEquals(2, sel.Length())
+	ut.AssertEquals("/surf", sel.First().AttrOr("href", ""))
+
+	sel, err = XPath(doc.Find("span"), "ancestor::div")
+	ut.AssertNil(err)
+	ut.AssertEquals(1, sel.Length())
+	ut.AssertTrue(sel.HasClass("results"))
+}
@@ -1,4 +1,10 @@
 // Package errors contains error types specific to the Surf library.
+//
+// Each error type keeps the details of the failure in its Fields, such as the
+// URL of the page and the expression which didn't match, and the error which
+// caused it, which is returned by Unwrap. The types work with errors.Is and
+// errors.As from the standard library, and errors.Is matches any error of the
+// same type when given the zero value, such as errors.Is(err, PageNotFound{}).
 package errors
 
 import (
@@ -6,9 +12,55 @@ import (
 	"fmt"
 )
 
+// Fields are the details of a failure, which are kept by each error type.
+// Fields which don't apply to the failure are left empty.
+type Fields struct {
+	// URL is the URL of the page which failed to load, or which the failure
+	// happened in.
+	URL string
+
+	// Expr is the selector or expression which failed to match.
+	Expr string
+
+	// Field is the name of the form field or attribute which failed.
+	Field string
+
+	// StatusCode is the status of the response which failed.
+	StatusCode int
+
+	// Err is the error which caused the failure.
+	Err error
+}
+
+// Unwrap returns the error which caused the failure.
+func (f Fields) Unwrap() error {
+	return f.Err
+}
+
+// merge returns the fields with the non-zero values of o set.
+func (f Fields) merge(o Fields) Fields {
+	if o.URL != "" {
+		f.URL = o.URL
+	}
+	if o.Expr != "" {
+		f.Expr = o.Expr
+	}
+	if o.Field != "" {
+		f.Field = o.Field
+	}
+	if o.StatusCode != 0 {
+		f.StatusCode = o.StatusCode
+	}
+	if o.Err != nil {
+		f.Err = o.Err
+	}
+	return f
+}
+
 // Error represents any generic error.
 type Error struct {
 	error
+	Fields
 }
 
 // New creates and returns an Error type.
@@ -19,10 +71,23 @@ func New(msg string, a ...interface{}) Error {
 	}
 }
 
+// With returns a copy of the error with the non-zero fields of f set.
+func (e Error) With(f Fields) Error {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is an Error type.
+func (e Error) Is(target error) bool {
+	_, ok := target.(Error)
+	return ok
+}
+
 // PageNotFound represents a failed attempt to visit a page because the page
 // does not exist.
 type PageNotFound struct {
 	error
+	Fields
 }
 
 // NewPageNotFound creates and returns a NotFound type.
@@ -33,9 +98,69 @@ func NewPageNotFound(msg string, a ...interface{}) PageNotFound {
 	}
 }
 
+// NewPageNotFoundStatus creates and returns a PageNotFound type for a
+// response with the 404 status. The error wraps an HTTPStatus type.
+func NewPageNotFoundStatus(state interface{}, msg string, a ...interface{}) PageNotFound {
+	status := NewHTTPStatus(404, state, "Not Found: "+msg, a...)
+	return PageNotFound{
+		error:  status.error,
+		Fields: Fields{StatusCode: 404, Err: status},
+	}
+}
+
+// With returns a copy of the error with the non-zero fields of f set.
+func (e PageNotFound) With(f Fields) PageNotFound {
+	e.Fields = e.Fields.merge(f)
+	if status, ok := e.Err.(HTTPStatus); ok && f.Err == nil {
+		e.Err = status.With(f)
+	}
+	return e
+}
+
+// Is returns true when target is a PageNotFound type.
+func (e PageNotFound) Is(target error) bool {
+	_, ok := target.(PageNotFound)
+	return ok
+}
+
+// HTTPStatus represents a response with an error status, which is a 4xx or
+// 5xx status. The status is kept in the StatusCode of the Fields.
+type HTTPStatus struct {
+	error
+	Fields
+
+	// State is the *jar.State of the page loaded from the response. It's an
+	// interface{} because the jar package imports this one.
+	State interface{}
+}
+
+// NewHTTPStatus creates and returns a HTTPStatus type.
+func NewHTTPStatus(code int, state interface{}, msg string, a ...interface{}) HTTPStatus {
+	msg = fmt.Sprintf(msg, a...)
+	return HTTPStatus{
+		error:  errors.New(msg),
+		Fields: Fields{StatusCode: code},
+		State:  state,
+	}
+}
+
+// With returns a copy of the error with the non-zero fields of f set.
+func (e HTTPStatus) With(f Fields) HTTPStatus {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a HTTPStatus type with the same status
+// code, or with a zero status code, which matches every status.
+func (e HTTPStatus) Is(target error) bool {
+	t, ok := target.(HTTPStatus)
+	return ok && (t.StatusCode == 0 || t.StatusCode == e.StatusCode)
+}
+
 // LinkNotFound represents a failed attempt to follow a link on a page.
 type LinkNotFound struct {
 	error
+	Fields
 }
 
 // NewLinkNotFound creates and returns a LinkNotFound type.
@@ -46,9 +171,22 @@ func NewLinkNotFound(msg string, a ...interface{}) LinkNotFound {
 	}
 }
 
+// With returns a copy of the error with the non-zero fields of f set.
+func (e LinkNotFound) With(f Fields) LinkNotFound {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a LinkNotFound type.
+func (e LinkNotFound) Is(target error) bool {
+	_, ok := target.(LinkNotFound)
+	return ok
+}
+
 // AttributeNotFound represents a failed attempt to read an element attribute.
 type AttributeNotFound struct {
 	error
+	Fields
 }
 
 // NewAttributeNotFound creates and returns a AttributeNotFound type.
@@ -59,9 +197,22 @@ func NewAttributeNotFound(msg string, a ...interface{}) AttributeNotFound {
 	}
 }
 
+// With returns a copy of the error with the non-zero fields of f set.
+func (e AttributeNotFound) With(f Fields) AttributeNotFound {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a AttributeNotFound type.
+func (e AttributeNotFound) Is(target error) bool {
+	_, ok := target.(AttributeNotFound)
+	return ok
+}
+
 // Location represents a failed attempt to follow a Location header.
 type Location struct {
 	error
+	Fields
 }
 
 // NewLocation creates and returns a Location type.
@@ -72,9 +223,22 @@ func NewLocation(msg string, a ...interface{}) Location {
 	}
 }
 
+// With returns a copy of the error with the non-zero fields of f set.
+func (e Location) With(f Fields) Location {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a Location type.
+func (e Location) Is(target error) bool {
+	_, ok := target.(Location)
+	return ok
+}
+
 // PageNotLoaded represents a failed attempt to operate on a non-loaded page.
 type PageNotLoaded struct {
 	error
+	Fields
 }
 
 // NewPageNotLoaded creates and returns a PageNotLoaded type.
@@ -85,9 +249,22 @@ func NewPageNotLoaded(msg string, a ...interface{}) PageNotLoaded {
 	}
 }
 
+// With returns a copy of the error with the non-zero fields of f set.
+func (e PageNotLoaded) With(f Fields) PageNotLoaded {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a PageNotLoaded type.
+func (e PageNotLoaded) Is(target error) bool {
+	_, ok := target.(PageNotLoaded)
+	return ok
+}
+
 // ElementNotFound represents a failed attempt to operate on a non-existent page element.
 type ElementNotFound struct {
 	error
+	Fields
 }
 
 // NewElementNotFound creates and returns a ElementNotFound type.
@@ -98,9 +275,22 @@ func NewElementNotFound(msg string, a ...interface{}) ElementNotFound {
 	}
 }
 
+// With returns a copy of the error with the non-zero fields of f set.
+func (e ElementNotFound) With(f Fields) ElementNotFound {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a ElementNotFound type.
+func (e ElementNotFound) Is(target error) bool {
+	_, ok := target.(ElementNotFound)
+	return ok
+}
+
 // InvalidFormValue represents a failed attempt to set a form value that is not valid.
 type InvalidFormValue struct {
 	error
+	Fields
 }
 
 // NewInvalidFormValue creates and returns a InvalidFormValue type.
@@ -110,3 +300,42 @@ func NewInvalidFormValue(msg string, a ...interface{}) InvalidFormValue {
 		error: errors.New(msg),
 	}
 }
+
+// With returns a copy of the error with the non-zero fields of f set.
+func (e InvalidFormValue) With(f Fields) InvalidFormValue {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a InvalidFormValue type.
+func (e InvalidFormValue) Is(target error) bool {
+	_, ok := target.(InvalidFormValue)
+	return ok
+}
+
+// UnsupportedMediaType represents a failed attempt to use the page in a way
+// its media type doesn't support, such as finding forms in a JSON document.
+type UnsupportedMediaType struct {
+	error
+	Fields
+}
+
+// NewUnsupportedMediaType creates and returns a UnsupportedMediaType type.
+func NewUnsupportedMediaType(msg string, a ...interface{}) UnsupportedMediaType {
+	msg = fmt.Sprintf(msg, a...)
+	return UnsupportedMediaType{
+		error: errors.New(msg),
+	}
+}
+
+// With returns a copy of the error with the non-zero fields of f set.
+func (e UnsupportedMediaType) With(f Fields) UnsupportedMediaType {
+	e.Fields = e.Fields.merge(f)
+	return e
+}
+
+// Is returns true when target is a UnsupportedMediaType type.
+func (e UnsupportedMediaType) Is(target error) bool {
+	_, ok := target.(UnsupportedMediaType)
+	return ok
+}
@@ -12,6 +12,25 @@ type State struct {
 	Request  *http.Request
 	Response *http.Response
 	Dom      *goquery.Document
+
+	// MediaType is the media type of the response, such as "text/html" or
+	// "application/json".
+	MediaType string
+
+	// Charset is the name of the encoding the page was decoded from, such
+	// as "utf-8" or "shift_jis".
+	Charset string
+
+	// Body is the content of the response as it was received, before it was
+	// decoded to UTF-8.
+	Body []byte
+
+	// Redirects are the responses which redirected the request to the page,
+	// in the order they were followed.
+	Redirects []*Redirect
+
+	// Timing holds how long the request which loaded the page took.
+	Timing Timing
 }
 
 // NewHistoryState creates and returns a new *State type.
@@ -0,0 +1,42 @@
+package jar
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Redirect is a response which redirected the browser to another page.
+type Redirect struct {
+	// Url is the URL of the request which was redirected.
+	Url *url.URL
+
+	// Method is the method of the request which was redirected.
+	Method string
+
+	// StatusCode is the status of the response, such as 302.
+	StatusCode int
+
+	// Location is the absolute URL the response redirected to.
+	Location *url.URL
+
+	// Header holds the headers of the response.
+	Header http.Header
+
+	// Cookies are the cookies set by the response.
+	Cookies []*http.Cookie
+}
+
+// NewRedirect creates and returns a *Redirect type from a redirect response.
+func NewRedirect(resp *http.Response) *Redirect {
+	r := &Redirect{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Cookies:    resp.Cookies(),
+	}
+	if resp.Request != nil {
+		r.Url = resp.Request.URL
+		r.Method = resp.Request.Method
+	}
+	r.Location, _ = resp.Location()
+	return r
+}
@@ -0,0 +1,43 @@
+package jar
+
+import "time"
+
+// Timing holds how long the request which loaded a page took, and the size
+// of the response.
+//
+// The times spent looking up hosts and connecting to them add up the
+// connections made for the request and its redirects, and they're zero when
+// connections are reused.
+type Timing struct {
+	// Start is when the request was sent.
+	Start time.Time
+
+	// DNS is the time spent looking up hosts.
+	DNS time.Duration
+
+	// Connect is the time spent opening connections.
+	Connect time.Duration
+
+	// TLS is the time spent on TLS handshakes.
+	TLS time.Duration
+
+	// TTFB is the time from the start of the request to the first byte of
+	// the response which loaded the page.
+	TTFB time.Duration
+
+	// Download is the time spent reading the body after the first byte.
+	Download time.Duration
+
+	// Total is the time from the start of the request until the body was read.
+	Total time.Duration
+
+	// Reused is true when the response was received on a reused connection.
+	Reused bool
+
+	// CompressedSize is the size of the body as it was sent, before any
+	// content encodings were decoded.
+	CompressedSize int64
+
+	// Size is the size of the decoded body.
+	Size int64
+}
@@ -20,6 +20,18 @@ var (
 	// DefaultFollowRedirects is the global value for the AttributeFollowRedirects attribute.
 	DefaultFollowRedirects = true
 
+	// DefaultJavaScript is the global value for the AttributeJavaScript attribute.
+	DefaultJavaScript = false
+
+	// DefaultSynchronousRefresh is the global value for the AttributeSynchronousRefresh attribute.
+	DefaultSynchronousRefresh = false
+
+	// DefaultStatusErrors is the global value for the AttributeStatusErrors attribute.
+	DefaultStatusErrors = false
+
+	// DefaultLogBodies is the global value for the AttributeLogBodies attribute.
+	DefaultLogBodies = false
+
 	// DefaultMaxHistoryLength is the global value for max history length.
 	DefaultMaxHistoryLength = 0
 )
@@ -39,6 +51,10 @@ func NewBrowser() *browser.Browser {
 		browser.SendReferer:         DefaultSendReferer,
 		browser.MetaRefreshHandling: DefaultMetaRefreshHandling,
 		browser.FollowRedirects:     DefaultFollowRedirects,
+		browser.JavaScript:          DefaultJavaScript,
+		browser.SynchronousRefresh:  DefaultSynchronousRefresh,
+		browser.StatusErrors:        DefaultStatusErrors,
+		browser.LogBodies:           DefaultLogBodies,
 	})
 
 	return bow